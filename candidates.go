@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+)
+
+// maxParallelRequests caps how many candidate requests run at once, so a large
+// --candidates does not hit a rate-limited API with a burst of requests.
+const maxParallelRequests = 3
+
+// candidate is one generated commit message together with its validation result.
+type candidate struct {
+	message string
+	problem error
+}
+
+// selectCandidate generates several messages and returns the one to use. Interactive
+// runs show a menu (true means the user picked one); otherwise the first valid
+// candidate is taken.
+func selectCandidate(ctx context.Context, analysisInput string, settings generationSettings, n int, run runOptions) (string, bool, error) {
+	candidates, err := generateCandidates(ctx, analysisInput, settings, n)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	if run.interactive() {
+		return chooseCandidate(ctx, candidates)
+	}
+	for _, c := range candidates {
+		if c.problem == nil {
+			return c.message, false, nil
+		}
+	}
+	return candidates[0].message, false, nil
+}
+
+// generateCandidates requests n messages, up to maxParallelRequests at a time,
+// spreading the requests over the provider list, and returns the distinct
+// cleaned messages in request order.
+func generateCandidates(ctx context.Context, analysisInput string, settings generationSettings, n int) ([]candidate, error) {
+	chain, err := buildProviderChain(settings)
+	if err != nil {
+		return nil, err
+	}
+	prompt := settings.prompt(analysisInput, "")
+
+	messages := make([]string, n)
+	errs := make([]error, n)
+	slots := make(chan struct{}, maxParallelRequests)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			messages[i], errs[i] = runProvider(ctx, chain.StartingAt(i), prompt)
+		}(i)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ErrInterrupted
+	}
+	return dedupeCandidates(messages, errs, settings.clean)
+}
+
+// dedupeCandidates cleans and validates the successful messages, dropping duplicates.
+func dedupeCandidates(messages []string, errs []error, clean func(string) string) ([]candidate, error) {
+	seen := make(map[string]bool, len(messages))
+	candidates := make([]candidate, 0, len(messages))
+	var failures []error
+	for i, message := range messages {
+		if errs[i] != nil {
+			failures = append(failures, errs[i])
+			continue
+		}
+		message = clean(message)
+		if message == "" || seen[message] {
+			continue
+		}
+		seen[message] = true
+		candidates = append(candidates, candidate{message: message, problem: commitrules.ValidateCommitMessage(message)})
+	}
+
+	if len(candidates) == 0 {
+		if len(failures) == 0 {
+			return nil, ErrNoCandidates
+		}
+		return nil, fmt.Errorf("all %d requests failed, first error: %w", len(failures), failures[0])
+	}
+	if len(failures) > 0 {
+		printWarning("%d of %d requests failed: %v", len(failures), len(messages), failures[0])
+	}
+	return candidates, nil
+}
+
+// chooseCandidate shows a numbered menu and returns the selected message.
+// It returns false when the user cancels.
+func chooseCandidate(ctx context.Context, candidates []candidate) (string, bool, error) {
+	fmt.Println("Generated commit messages:")
+	for i, c := range candidates {
+		status := "valid"
+		if c.problem != nil {
+			status = "invalid: " + c.problem.Error()
+		}
+		// Indent body lines so multi-line messages stay under their number.
+		message := strings.ReplaceAll(c.message, "\n", "\n     ")
+		fmt.Printf("  %d) %s  [%s]\n", i+1, message, status)
+	}
+	fmt.Println()
+
+	for {
+		fmt.Printf("Choose a message [1-%d], or n to cancel: ", len(candidates))
+		response, err := readLine(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println()
+				return "", false, ctx.Err()
+			}
+			return "", false, nil
+		}
+
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response == "" || response == "n" || response == "no" {
+			return "", false, nil
+		}
+		if choice, err := strconv.Atoi(response); err == nil && choice >= 1 && choice <= len(candidates) {
+			return candidates[choice-1].message, true, nil
+		}
+		fmt.Printf("Please enter a number between 1 and %d.\n", len(candidates))
+	}
+}
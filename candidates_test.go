@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+)
+
+func TestDedupeCandidates(t *testing.T) {
+	messages := []string{`"feat(api): add retries"`, "feat(api): add retries", "", "Add retries", "fix(api): retry on 503"}
+	errs := []error{nil, nil, errors.New("exit status 1"), nil, nil}
+
+	candidates, err := dedupeCandidates(messages, errs, commitrules.CleanCommitMessage)
+	if err != nil {
+		t.Fatalf("dedupeCandidates returned error: %v", err)
+	}
+
+	want := []string{"feat(api): add retries", "Add retries", "fix(api): retry on 503"}
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d: %+v", len(candidates), len(want), candidates)
+	}
+	for i, c := range candidates {
+		if c.message != want[i] {
+			t.Errorf("candidate %d = %q, want %q", i, c.message, want[i])
+		}
+	}
+	if candidates[0].problem != nil || candidates[1].problem == nil {
+		t.Errorf("validation status not recorded: %+v", candidates)
+	}
+}
+
+func TestDedupeCandidatesAllFailed(t *testing.T) {
+	failure := errors.New("exit status 1")
+	_, err := dedupeCandidates([]string{"", ""}, []error{failure, failure}, commitrules.CleanCommitMessage)
+	if !errors.Is(err, failure) {
+		t.Errorf("dedupeCandidates error = %v, want the provider failure", err)
+	}
+
+	_, err = dedupeCandidates([]string{"  "}, []error{nil}, commitrules.CleanCommitMessage)
+	if !errors.Is(err, ErrNoCandidates) {
+		t.Errorf("dedupeCandidates error = %v, want ErrNoCandidates", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/FreePeak/commitgen/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+func createConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Show or change settings (defaults < user config < .commitgen.yaml < COMMITGEN_* < flags)",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "Show every setting with its effective value and where it comes from",
+				Action: listConfig,
+			},
+			{
+				Name:      "get",
+				Usage:     "Print the effective value of a setting",
+				ArgsUsage: "<key>",
+				Action:    getConfig,
+			},
+			{
+				Name:      "set",
+				Usage:     "Write a setting to the user config, or to the repository config with --repo",
+				ArgsUsage: "<key> <value>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "repo",
+						Usage: "Write to " + config.RepoFileName + " at the repository root",
+					},
+				},
+				Action: setConfig,
+			},
+		},
+	}
+}
+
+func listConfig(cliContext *cli.Context) error {
+	cfg, err := loadConfig(cliContext.Context)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE")
+	for _, key := range cfg.Keys() {
+		value, err := cfg.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", key, value, cfg.Source(key))
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return nil
+}
+
+func getConfig(cliContext *cli.Context) error {
+	if cliContext.NArg() != 1 {
+		return fmt.Errorf("%w: config get <key>", ErrWrongArguments)
+	}
+	cfg, err := loadConfig(cliContext.Context)
+	if err != nil {
+		return err
+	}
+
+	value, err := cfg.Get(cliContext.Args().First())
+	if err != nil {
+		return fmt.Errorf("%w (see commitgen config list)", err)
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func setConfig(cliContext *cli.Context) error {
+	if cliContext.NArg() != 2 {
+		return fmt.Errorf("%w: config set [--repo] <key> <value>", ErrWrongArguments)
+	}
+	key, value := cliContext.Args().Get(0), cliContext.Args().Get(1)
+
+	path, err := config.UserPath()
+	if err != nil {
+		return fmt.Errorf("failed to locate user config: %w", err)
+	}
+	if cliContext.Bool("repo") {
+		root := getRepoRoot(cliContext.Context)
+		if root == "" {
+			return ErrNotGitRepo
+		}
+		path = config.RepoPath(root)
+	}
+
+	if err := config.Set(path, key, value); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	fmt.Printf("Set %s = %s in %s\n", key, value, path)
+	return nil
+}
@@ -13,7 +13,7 @@ class Commitgen < Formula
   depends_on "go" => :build
 
   def install
-    system "go", "build", *std_go_args(ldflags: "-s -w"), "-o", bin/"commitgen", "main.go"
+    system "go", "build", *std_go_args(ldflags: "-s -w"), "-o", bin/"commitgen", "."
   end
 
   test do
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Errors returned by the hook commands.
+var (
+	ErrHookConflict    = errors.New("cannot chain existing hook")
+	ErrUnsupportedHook = errors.New("unsupported hook")
+)
+
+// hookMarker identifies hooks written by commitgen.
+const hookMarker = "# Installed by commitgen hook install; remove with commitgen hook uninstall."
+
+// chainedSuffix is appended to a hook that existed before commitgen was
+// installed. The commitgen hook runs it first.
+const chainedSuffix = ".commitgen-chained"
+
+// Hook names.
+const (
+	hookPrepareCommitMsg = "prepare-commit-msg"
+	hookCommitMsg        = "commit-msg"
+)
+
+// managedHooks are the hooks commitgen installs, with the command each runs.
+var managedHooks = []struct {
+	name    string
+	command string
+}{
+	{hookPrepareCommitMsg, `hook run prepare-commit-msg "$@"`},
+	{hookCommitMsg, `lint --message-file "$1"`},
+}
+
+func createHookCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "hook",
+		Usage: "Manage the git hooks that generate and lint commit messages",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "install",
+				Usage:  "Install prepare-commit-msg and commit-msg hooks; existing hooks keep running first",
+				Action: installHooks,
+			},
+			{
+				Name:   "uninstall",
+				Usage:  "Remove the commitgen hooks and restore the hooks they replaced",
+				Action: uninstallHooks,
+			},
+			{
+				Name:   "status",
+				Usage:  "Show where hooks are read from and which ones commitgen manages",
+				Action: showHookStatus,
+			},
+			{
+				Name:      "run",
+				Usage:     "Entry point for the installed hooks",
+				ArgsUsage: "<hook> <args>...",
+				Hidden:    true,
+				Action:    runHook,
+			},
+		},
+	}
+}
+
+// hooksDir returns the directory git runs hooks from, honouring core.hooksPath.
+func hooksDir(ctx context.Context) (string, error) {
+	if !isGitRepo() {
+		return "", ErrNotGitRepo
+	}
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate hooks directory: %w", err)
+	}
+	dir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", fmt.Errorf("failed to locate hooks directory: %w", err)
+	}
+	return dir, nil
+}
+
+func installHooks(cliContext *cli.Context) error {
+	dir, err := hooksDir(cliContext.Context)
+	if err != nil {
+		return err
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate commitgen: %w", err)
+	}
+
+	for _, hook := range managedHooks {
+		chained, err := installHook(dir, hook.name, hookScript(binary, hook.name, hook.command))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, hook.name)
+		if chained {
+			fmt.Printf("Installed %s (the existing hook was moved to %s and still runs first)\n", path, path+chainedSuffix)
+		} else {
+			fmt.Printf("Installed %s\n", path)
+		}
+	}
+	return nil
+}
+
+// installHook writes script as the named hook. An existing hook that commitgen
+// did not write is renamed so the new hook can chain to it; chained reports
+// whether that happened.
+func installHook(dir, name, script string) (chained bool, err error) {
+	path := filepath.Join(dir, name)
+	managed, exists, err := readHook(path)
+	if err != nil {
+		return false, err
+	}
+	if exists && !managed {
+		if _, err := os.Stat(path + chainedSuffix); err == nil {
+			return false, fmt.Errorf("%w: %s and %s both exist", ErrHookConflict, path, path+chainedSuffix)
+		}
+		if err := os.Rename(path, path+chainedSuffix); err != nil {
+			return false, fmt.Errorf("failed to move existing hook: %w", err)
+		}
+		chained = true
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	//nolint:gosec // G306: hooks must be executable
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return false, fmt.Errorf("failed to write hook: %w", err)
+	}
+	return chained, nil
+}
+
+// hookScript returns a hook that runs the chained hook, if any, and then
+// commitgen with the given arguments.
+func hookScript(binary, name, command string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s
+chained="$(dirname "$0")/%s%s"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+exec %s %s
+`, hookMarker, name, chainedSuffix, shellQuote(binary), command)
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// readHook reports whether a hook exists and whether commitgen wrote it.
+func readHook(path string) (managed, exists bool, err error) {
+	//nolint:gosec // G304: path is a hook in the repository's hooks directory
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read hook: %w", err)
+	}
+	return strings.Contains(string(data), hookMarker), true, nil
+}
+
+func uninstallHooks(cliContext *cli.Context) error {
+	dir, err := hooksDir(cliContext.Context)
+	if err != nil {
+		return err
+	}
+	for _, hook := range managedHooks {
+		message, err := uninstallHook(dir, hook.name)
+		if err != nil {
+			return err
+		}
+		fmt.Println(message)
+	}
+	return nil
+}
+
+// uninstallHook removes the commitgen hook and puts back the hook it chained.
+// Hooks commitgen did not write are left alone.
+func uninstallHook(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	managed, exists, err := readHook(path)
+	switch {
+	case err != nil:
+		return "", err
+	case !exists:
+		return fmt.Sprintf("%s is not installed", path), nil
+	case !managed:
+		return fmt.Sprintf("Left %s alone: it was not installed by commitgen", path), nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove hook: %w", err)
+	}
+	if _, err := os.Stat(path + chainedSuffix); err == nil {
+		if err := os.Rename(path+chainedSuffix, path); err != nil {
+			return "", fmt.Errorf("failed to restore chained hook: %w", err)
+		}
+		return fmt.Sprintf("Removed %s and restored the previous hook", path), nil
+	}
+	return fmt.Sprintf("Removed %s", path), nil
+}
+
+func showHookStatus(cliContext *cli.Context) error {
+	ctx := cliContext.Context
+	dir, err := hooksDir(ctx)
+	if err != nil {
+		return err
+	}
+
+	location := dir
+	if output, err := exec.CommandContext(ctx, "git", "config", "core.hooksPath").Output(); err == nil {
+		location += " (core.hooksPath " + strings.TrimSpace(string(output)) + ")"
+	}
+	fmt.Printf("Hooks directory: %s\n", location)
+
+	for _, hook := range managedHooks {
+		path := filepath.Join(dir, hook.name)
+		managed, exists, err := readHook(path)
+		if err != nil {
+			return err
+		}
+		status := "not installed"
+		switch {
+		case managed:
+			status = "installed"
+			if _, err := os.Stat(path + chainedSuffix); err == nil {
+				status += ", runs " + hook.name + chainedSuffix + " first"
+			}
+		case exists:
+			status = "other hook present (not managed by commitgen)"
+		}
+		fmt.Printf("  %-20s %s\n", hook.name, status)
+	}
+	return nil
+}
+
+// runHook is called by the installed hooks. It never prompts: a hook's stdin
+// is not the user's terminal.
+func runHook(cliContext *cli.Context) error {
+	args := cliContext.Args().Slice()
+	if len(args) < 2 || args[0] != hookPrepareCommitMsg {
+		return fmt.Errorf("%w: hook run %s <file> [<source> [<sha>]]", ErrUnsupportedHook, hookPrepareCommitMsg)
+	}
+	var source string
+	if len(args) > 2 {
+		source = args[2]
+	}
+
+	ctx := cliContext.Context
+	generate := func() (string, error) {
+		cfg, err := loadGenerationConfig(cliContext)
+		if err != nil {
+			return "", err
+		}
+
+		settings := getGenerationSettings(cliContext, cfg)
+		analysisInput, _, err := getAnalysisInput(ctx, "staged", settings.analysisTokens())
+		if err != nil {
+			return "", err
+		}
+		message, err := callAIAPI(ctx, settings.prompt(analysisInput, ""), settings)
+		if err != nil {
+			return "", err
+		}
+		return settings.clean(message), nil
+	}
+
+	// A failed generation must not block the commit; the user just gets the
+	// usual empty message to write.
+	if err := fillMessageFile(args[1], source, generate); err != nil {
+		printWarning("commitgen could not prepare a commit message: %v", err)
+	}
+	return nil
+}
+
+// fillMessageFile puts a generated message above the comments git wrote to
+// the message file. It does nothing when git already has a message, i.e. for
+// -m, -F, templates, merges, squashes and amends, or when the file already
+// contains text.
+func fillMessageFile(path, source string, generate func() (string, error)) error {
+	if source != "" {
+		return nil
+	}
+	//nolint:gosec // G304: path is the message file git passed to the hook
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read message file: %w", err)
+	}
+	if stripComments(string(data)) != "" {
+		return nil
+	}
+
+	message, err := generate()
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		return ErrNoCandidates
+	}
+	//nolint:gosec // G306: keep git's own permissions for the message file
+	if err := os.WriteFile(path, []byte(message+"\n"+string(data)), 0o644); err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runMainEnv makes the test binary run commitgen itself, so installed hooks
+// can call it.
+const runMainEnv = "COMMITGEN_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestInstallHookChainsAndUninstallRestores(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, hookCommitMsg)
+	existing := "#!/bin/sh\nexit 0\n"
+	if err := os.WriteFile(path, []byte(existing), 0o755); err != nil {
+		t.Fatalf("failed to write hook: %v", err)
+	}
+
+	script := hookScript("/opt/it's/commitgen", hookCommitMsg, `lint --message-file "$1"`)
+	chained, err := installHook(dir, hookCommitMsg, script)
+	if err != nil || !chained {
+		t.Fatalf("installHook = %v, %v; want the existing hook chained", chained, err)
+	}
+	if data, _ := os.ReadFile(path + chainedSuffix); string(data) != existing {
+		t.Errorf("chained hook = %q, want the original", data)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `exec '/opt/it'\''s/commitgen' lint --message-file "$1"`) {
+		t.Errorf("hook script = %q", data)
+	}
+
+	// Reinstalling only rewrites the commitgen hook.
+	if chained, err := installHook(dir, hookCommitMsg, script); err != nil || chained {
+		t.Errorf("reinstall = %v, %v; want no new chaining", chained, err)
+	}
+
+	if _, err := uninstallHook(dir, hookCommitMsg); err != nil {
+		t.Fatalf("uninstallHook returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != existing {
+		t.Errorf("restored hook = %q, want the original", data)
+	}
+	if _, err := os.Stat(path + chainedSuffix); !os.IsNotExist(err) {
+		t.Error("chained hook should have been moved back")
+	}
+}
+
+func TestInstalledHooksAcceptMerges(t *testing.T) {
+	dir := initRepo(t)
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to locate test binary: %v", err)
+	}
+	t.Setenv(runMainEnv, "1")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	hooks, err := hooksDir(context.Background())
+	if err != nil {
+		t.Fatalf("hooksDir returned error: %v", err)
+	}
+	for _, hook := range managedHooks {
+		if _, err := installHook(hooks, hook.name, hookScript(binary, hook.name, hook.command)); err != nil {
+			t.Fatalf("installHook(%s) returned error: %v", hook.name, err)
+		}
+	}
+
+	git := func(args ...string) bool {
+		output, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Logf("git %v failed: %v\n%s", args, err, output)
+		}
+		return err == nil
+	}
+	commit := func(name, message string) bool {
+		writeTestFile(t, filepath.Join(dir, name))
+		return git("add", name) && git("commit", "-q", "-m", message)
+	}
+
+	if !git("checkout", "-q", "-b", "side") || !commit("side.txt", "feat: add side file") ||
+		!git("checkout", "-q", "-") || !commit("main.txt", "feat: add main file") {
+		t.Fatal("failed to create the branches to merge")
+	}
+	if !git("merge", "--no-edit", "side") {
+		t.Error("the hooks should accept git's merge message")
+	}
+	if !git("revert", "--no-edit", "side") {
+		t.Error("the hooks should accept git's revert message")
+	}
+	if commit("a.txt", "Added a file") {
+		t.Error("the commit-msg hook should reject a non-conventional message")
+	}
+}
+
+func TestInstallHookConflict(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{hookCommitMsg, hookCommitMsg + chainedSuffix} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatalf("failed to write hook: %v", err)
+		}
+	}
+	if _, err := installHook(dir, hookCommitMsg, "script"); !errors.Is(err, ErrHookConflict) {
+		t.Errorf("installHook error = %v, want ErrHookConflict", err)
+	}
+}
+
+func TestFillMessageFile(t *testing.T) {
+	comments := "\n# Please enter the commit message for your changes.\n"
+	generate := func() (string, error) { return "feat(api): add widgets", nil }
+
+	tests := []struct {
+		name    string
+		content string
+		source  string
+		want    string
+	}{
+		{"plain commit", comments, "", "feat(api): add widgets\n" + comments},
+		{"commit -m", "fix: typo\n" + comments, "message", "fix: typo\n" + comments},
+		{"amend", "fix: typo\n" + comments, "commit", "fix: typo\n" + comments},
+		{"existing text", "wip\n" + comments, "", "wip\n" + comments},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatalf("failed to write message file: %v", err)
+		}
+		if err := fillMessageFile(path, tt.source, generate); err != nil {
+			t.Errorf("%s: fillMessageFile returned error: %v", tt.name, err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tt.want {
+			t.Errorf("%s: message file = %q, want %q", tt.name, data, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+	"github.com/urfave/cli/v2"
+)
+
+// Lint output formats.
+const (
+	formatHuman  = "human"
+	formatJSON   = "json"
+	formatGitHub = "github"
+)
+
+// Errors returned by the lint command.
+var (
+	ErrLintFailed    = errors.New("commit message lint failed")
+	ErrUnknownFormat = errors.New("unknown output format")
+)
+
+// lintedMessage is one linted commit, or the message file when Commit is empty.
+type lintedMessage struct {
+	Commit string `json:"commit,omitempty"`
+	Header string `json:"header"`
+	// Ignored is true for merge, revert, fixup! and squash! commits.
+	Ignored  bool                  `json:"ignored,omitempty"`
+	Findings []commitrules.Finding `json:"findings"`
+}
+
+func createLintCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lint",
+		Usage:     "Check existing commit messages against the commit rules (the last commit by default)",
+		ArgsUsage: "[<rev-range>]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "message-file",
+				Usage: "Check the message in `FILE` instead of commits, e.g. from a commit-msg hook (- reads stdin)",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format: human, json or github (workflow annotations)",
+				Value: formatHuman,
+			},
+		},
+		Action: lintCommits,
+	}
+}
+
+func lintCommits(cliContext *cli.Context) error {
+	ctx := cliContext.Context
+	format := cliContext.String("format")
+	if format != formatHuman && format != formatJSON && format != formatGitHub {
+		return fmt.Errorf("%w %q: use human, json or github", ErrUnknownFormat, format)
+	}
+	if cliContext.NArg() > 1 {
+		return fmt.Errorf("%w: lint [<rev-range>]", ErrWrongArguments)
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	applyRules(ctx, cfg)
+
+	var messages []lintedMessage
+	if path := cliContext.String("message-file"); path != "" {
+		if cliContext.NArg() > 0 {
+			return fmt.Errorf("%w: a rev-range cannot be combined with --message-file", ErrWrongArguments)
+		}
+		message, err := readMessageFile(path)
+		if err != nil {
+			return err
+		}
+		messages = []lintedMessage{lintMessage("", message)}
+	} else {
+		messages, err = lintRange(ctx, cliContext.Args().First())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeLintReport(os.Stdout, format, messages); err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, message := range messages {
+		for _, finding := range message.Findings {
+			if finding.Severity == commitrules.LevelError {
+				errorCount++
+			}
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%w: %d error(s)", ErrLintFailed, errorCount)
+	}
+	return nil
+}
+
+// readMessageFile reads a commit message file the way git commit does,
+// dropping comment lines and the diff below a scissors line.
+func readMessageFile(path string) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		//nolint:gosec // G304: the user names the file to lint
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+	return stripComments(string(data)), nil
+}
+
+// lintRange lints every non-merge commit in revRange, or the last commit when
+// revRange is empty.
+func lintRange(ctx context.Context, revRange string) ([]lintedMessage, error) {
+	if !isGitRepo() {
+		return nil, ErrNotGitRepo
+	}
+	args := []string{"log", "-z", "--no-merges", "--format=%H%n%B"}
+	if revRange == "" {
+		args = append(args, "-1", "HEAD")
+	} else {
+		args = append(args, revRange)
+	}
+	// "--" keeps a range that looks like a file name from being read as a path.
+	args = append(args, "--")
+
+	var stderr bytes.Buffer
+	//nolint:gosec // G204: revRange is passed as a single argument, never through a shell
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s failed: %w: %s", revRange, err, strings.TrimSpace(stderr.String()))
+	}
+	return lintLog(string(output)), nil
+}
+
+// lintLog lints the output of git log -z --format=%H%n%B.
+func lintLog(output string) []lintedMessage {
+	var messages []lintedMessage
+	for _, entry := range strings.Split(output, "\x00") {
+		commit, message, _ := strings.Cut(strings.TrimLeft(entry, "\n"), "\n")
+		if commit == "" {
+			continue
+		}
+		messages = append(messages, lintMessage(commit, message))
+	}
+	return messages
+}
+
+// lintMessage lints one message, skipping the ones git writes itself such as
+// merges and reverts, as commitlint does.
+func lintMessage(commit, message string) lintedMessage {
+	linted := lintedMessage{Commit: commit, Header: firstLine(message)}
+	if commitrules.Ignored(message) {
+		linted.Ignored = true
+		return linted
+	}
+	linted.Findings = commitrules.Lint(message)
+	return linted
+}
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+func writeLintReport(w io.Writer, format string, messages []lintedMessage) error {
+	var err error
+	switch format {
+	case formatJSON:
+		// Encode empty lists as [] rather than null.
+		if messages == nil {
+			messages = []lintedMessage{}
+		}
+		for i := range messages {
+			if messages[i].Findings == nil {
+				messages[i].Findings = []commitrules.Finding{}
+			}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(messages)
+	case formatGitHub:
+		err = writeGitHubAnnotations(w, messages)
+	default:
+		err = writeHumanReport(w, messages)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write lint report: %w", err)
+	}
+	return nil
+}
+
+func writeHumanReport(w io.Writer, messages []lintedMessage) error {
+	var errorCount, warningCount int
+	for _, message := range messages {
+		if len(message.Findings) == 0 {
+			continue
+		}
+		name := "message"
+		if message.Commit != "" {
+			name = shortCommit(message.Commit)
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", name, message.Header); err != nil {
+			return err
+		}
+		for _, finding := range message.Findings {
+			if finding.Severity == commitrules.LevelError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+			if _, err := fmt.Fprintf(w, "  %s\n", finding.String()); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d message(s) checked: %d error(s), %d warning(s)\n", len(messages), errorCount, warningCount)
+	return err
+}
+
+// writeGitHubAnnotations prints workflow commands that GitHub Actions shows
+// as annotations on the run.
+func writeGitHubAnnotations(w io.Writer, messages []lintedMessage) error {
+	for _, message := range messages {
+		name := "commit message"
+		if message.Commit != "" {
+			name = "commit " + shortCommit(message.Commit)
+		}
+		for _, finding := range message.Findings {
+			command := "warning"
+			if finding.Severity == commitrules.LevelError {
+				command = "error"
+			}
+			title := fmt.Sprintf("%s [%s]", name, finding.RuleID)
+			text := fmt.Sprintf("%s (line %d, column %d): %s",
+				message.Header, finding.Position.Line, finding.Position.Column, finding.Message)
+			if _, err := fmt.Fprintf(w, "::%s title=%s::%s\n", command, escapeProperty(title), escapeData(text)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command message.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+)
+
+func TestLintLog(t *testing.T) {
+	output := "1111111111111111111111111111111111111111\nfeat(api): add widgets\n\nBody text.\n\x00" +
+		"2222222222222222222222222222222222222222\nAdded widgets\n\x00"
+
+	messages := lintLog(output)
+	if len(messages) != 2 {
+		t.Fatalf("lintLog returned %d messages, want 2", len(messages))
+	}
+	if messages[0].Header != "feat(api): add widgets" || len(messages[0].Findings) != 0 {
+		t.Errorf("messages[0] = %+v, want a clean commit", messages[0])
+	}
+	if !commitrules.HasErrors(messages[1].Findings) || messages[1].Findings[0].RuleID != commitrules.RuleHeaderFormat {
+		t.Errorf("messages[1] = %+v, want a header-format error", messages[1])
+	}
+}
+
+func TestLintMessageIgnoresGitMessages(t *testing.T) {
+	for _, message := range []string{
+		"Merge branch 'side'",
+		"Merge branch 'main' of github.com:FreePeak/commitgen\n\n# Conflicts:\n#\tmain.go",
+		"Merge pull request #12 from user/topic",
+		"Merge remote-tracking branch 'origin/main'",
+		"Merge tag 'v1.2.0'",
+		"Revert \"feat: add widgets\"\n\nThis reverts commit 1111111.",
+		"fixup! feat: add widgets",
+		"squash! feat: add widgets",
+	} {
+		linted := lintMessage("", message)
+		if !linted.Ignored || len(linted.Findings) != 0 {
+			t.Errorf("lintMessage(%q) = %+v, want it ignored", message, linted)
+		}
+	}
+
+	for _, message := range []string{"Merged widgets", "Reverted the widgets"} {
+		if linted := lintMessage("", message); linted.Ignored || !commitrules.HasErrors(linted.Findings) {
+			t.Errorf("lintMessage(%q) = %+v, want a header-format error", message, linted)
+		}
+	}
+
+	output := "1111111111111111111111111111111111111111\nRevert \"feat: add widgets\"\n\x00"
+	if messages := lintLog(output); len(messages) != 1 || !messages[0].Ignored {
+		t.Errorf("lintLog = %+v, want the revert ignored", messages)
+	}
+}
+
+func TestWriteLintReport(t *testing.T) {
+	messages := []lintedMessage{{
+		Commit:   "2222222222222222222222222222222222222222",
+		Header:   "Added widgets",
+		Findings: commitrules.Lint("Added widgets"),
+	}}
+
+	var human bytes.Buffer
+	if err := writeLintReport(&human, formatHuman, messages); err != nil {
+		t.Fatalf("human report failed: %v", err)
+	}
+	if !strings.Contains(human.String(), "2222222 Added widgets\n  1:1 error") {
+		t.Errorf("human report = %q", human.String())
+	}
+
+	var github bytes.Buffer
+	if err := writeLintReport(&github, formatGitHub, messages); err != nil {
+		t.Fatalf("github report failed: %v", err)
+	}
+	if !strings.HasPrefix(github.String(), "::error title=commit 2222222 [header-format]::Added widgets (line 1, column 1): ") {
+		t.Errorf("github report = %q", github.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeLintReport(&out, formatJSON, messages); err != nil {
+		t.Fatalf("json report failed: %v", err)
+	}
+	var decoded []struct {
+		Findings []struct {
+			Rule     string `json:"rule"`
+			Severity string `json:"severity"`
+		} `json:"findings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("json report is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded) != 1 || decoded[0].Findings[0].Rule != "header-format" || decoded[0].Findings[0].Severity != "error" {
+		t.Errorf("json report = %s", out.String())
+	}
+}
+
+func TestStripCommentsCutsScissors(t *testing.T) {
+	message := "fix: handle nil rows\n\n# Please enter the commit message\n" + scissorsLine + "\ndiff --git a/x b/x\n"
+	if got := stripComments(message); got != "fix: handle nil rows" {
+		t.Errorf("stripComments = %q", got)
+	}
+}
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/FreePeak/commitgen/pkg/budget"
+	"github.com/FreePeak/commitgen/pkg/changeset"
+	"github.com/FreePeak/commitgen/pkg/commitlint"
 	"github.com/FreePeak/commitgen/pkg/commitrules"
+	"github.com/FreePeak/commitgen/pkg/config"
+	"github.com/FreePeak/commitgen/pkg/provider"
 	"github.com/urfave/cli/v2"
 )
 
@@ -27,13 +38,23 @@ var (
 	ErrNoChangesFound      = errors.New("no changes found to analyze")
 	ErrNoStagedFiles       = errors.New("no staged files found")
 	ErrNoUntrackedFiles    = errors.New("no untracked files found")
-	ErrUnsupportedProvider = errors.New("unsupported provider")
+	ErrUnsupportedProvider = provider.ErrUnsupportedProvider
 	ErrPermissionDenied    = errors.New("permission denied. Try: sudo commitgen install")
+	ErrProviderTimeout     = provider.ErrTimeout
+	ErrInterrupted         = errors.New("interrupted")
+	ErrNoCandidates        = errors.New("providers returned no usable commit message")
+	ErrNotInteractive      = errors.New("stdin is not a terminal; use --yes to commit, --print to output the message or --dry-run to preview")
+	ErrConflictingModes    = errors.New("--yes, --dry-run and --print cannot be combined")
+	ErrWrongArguments      = errors.New("wrong number of arguments")
 )
 
 func main() {
-	app := createApp()
-	if err := app.Run(os.Args); err != nil {
+	// Ctrl-C and SIGTERM cancel the context instead of killing commitgen outright,
+	// so running providers and git commands are shut down rather than orphaned.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := createApp().RunContext(ctx, os.Args)
+	stop()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -43,15 +64,12 @@ func createApp() *cli.App {
 		Name:    "commitgen",
 		Version: version,
 		Usage:   "AI-powered git commit message generator",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "provider",
-				Usage: "AI provider to use (claude*, gemini, copilot)",
-				Value: "claude",
-			},
-		},
+		Flags:   generationFlags(),
 		Commands: []*cli.Command{
 			createCommitCommand(),
+			createConfigCommand(),
+			createLintCommand(),
+			createHookCommand(),
 			{
 				Name:   "install",
 				Usage:  "Install commitgen to /usr/local/bin",
@@ -65,6 +83,90 @@ func createApp() *cli.App {
 	}
 }
 
+// generationFlags returns the flags shared by the root command and every commit subcommand.
+func generationFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "provider",
+			Usage:       "AI provider to use (claude*, gemini, copilot, anthropic, openai-compatible, ollama, or one from your config); a comma-separated list is tried in order",
+			DefaultText: provider.NameClaude,
+			EnvVars:     []string{"COMMITGEN_PROVIDER"},
+		},
+		&cli.StringFlag{
+			Name:    "model",
+			Usage:   "Model name for the first provider when it is an HTTP provider (defaults to the provider's own)",
+			EnvVars: []string{"COMMITGEN_MODEL"},
+		},
+		&cli.StringFlag{
+			Name:    "base-url",
+			Usage:   "Base URL for the first provider when it is an HTTP provider, e.g. a local stub or gateway",
+			EnvVars: []string{"COMMITGEN_BASE_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "api-key",
+			Usage:   "API key for the first provider when it is an HTTP provider (defaults to the provider's own variable)",
+			EnvVars: []string{"COMMITGEN_API_KEY"},
+		},
+		&cli.Float64Flag{
+			Name:    "temperature",
+			Usage:   "Sampling temperature for HTTP providers",
+			EnvVars: []string{"COMMITGEN_TEMPERATURE"},
+		},
+		&cli.IntFlag{
+			Name:    "max-tokens",
+			Usage:   "Maximum tokens in the generated answer for HTTP providers",
+			EnvVars: []string{"COMMITGEN_MAX_TOKENS"},
+		},
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Maximum time to wait for the provider (0 disables the limit)",
+			DefaultText: config.DefaultTimeout.String(),
+			EnvVars:     []string{"COMMITGEN_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:        "retries",
+			Usage:       "Retries per provider after a transient failure (non-zero exit, HTTP 429/5xx, empty output)",
+			DefaultText: strconv.Itoa(provider.DefaultRetries),
+			EnvVars:     []string{"COMMITGEN_RETRIES"},
+		},
+		&cli.DurationFlag{
+			Name:        "retry-backoff",
+			Usage:       "Delay before the first retry; doubles on each further retry",
+			DefaultText: provider.DefaultBackoff.String(),
+			EnvVars:     []string{"COMMITGEN_RETRY_BACKOFF"},
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Usage:   "Show provider command lines, stderr and timings as they happen",
+			EnvVars: []string{"COMMITGEN_VERBOSE"},
+		},
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Commit the generated message without asking",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Show what would be staged and committed without changing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "print",
+			Usage: "Only print the cleaned message to stdout (status goes to stderr); default when neither stdin nor stdout is a terminal",
+		},
+		&cli.IntFlag{
+			Name:        "candidates",
+			Usage:       "Generate up to N messages (spread across all listed providers) and pick one from a menu",
+			DefaultText: strconv.Itoa(config.DefaultCandidates),
+			EnvVars:     []string{"COMMITGEN_CANDIDATES"},
+		},
+		&cli.BoolFlag{
+			Name:    "body",
+			Usage:   "Generate a full message with a wrapped body and footers instead of a subject line",
+			EnvVars: []string{"COMMITGEN_BODY"},
+		},
+	}
+}
+
 func createCommitCommand() *cli.Command {
 	return &cli.Command{
 		Name:    "commit",
@@ -74,6 +176,7 @@ func createCommitCommand() *cli.Command {
 			createStagedCommand(),
 			createAllCommand(),
 			createUntrackedCommand(),
+			createSplitCommand(),
 		},
 	}
 }
@@ -83,14 +186,8 @@ func createStagedCommand() *cli.Command {
 		Name:    "staged",
 		Aliases: []string{"s"},
 		Usage:   "Generate from staged files",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "provider",
-				Usage: "AI provider to use (claude*, gemini, copilot)",
-				Value: "claude",
-			},
-		},
-		Action: generateCommitMessage("staged"),
+		Flags:   generationFlags(),
+		Action:  generateCommitMessage("staged"),
 	}
 }
 
@@ -99,14 +196,8 @@ func createAllCommand() *cli.Command {
 		Name:    "all",
 		Aliases: []string{"a"},
 		Usage:   "Generate from all changes",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "provider",
-				Usage: "AI provider to use (claude*, gemini, copilot)",
-				Value: "claude",
-			},
-		},
-		Action: generateCommitMessage("all"),
+		Flags:   generationFlags(),
+		Action:  generateCommitMessage("all"),
 	}
 }
 
@@ -115,14 +206,8 @@ func createUntrackedCommand() *cli.Command {
 		Name:    "untracked",
 		Aliases: []string{"u"},
 		Usage:   "Generate from untracked files",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "provider",
-				Usage: "AI provider to use (claude*, gemini, copilot)",
-				Value: "claude",
-			},
-		},
-		Action: generateCommitMessage("untracked"),
+		Flags:   generationFlags(),
+		Action:  generateCommitMessage("untracked"),
 	}
 }
 
@@ -143,311 +228,657 @@ func createVersionCommand() *cli.Command {
 
 func generateCommitMessage(mode string) cli.ActionFunc {
 	return func(cliContext *cli.Context) error {
+		ctx := cliContext.Context
 		if !isGitRepo() {
 			return ErrNotGitRepo
 		}
+		// git reports paths relative to the repository root, so analyse and
+		// stage from there wherever commitgen was started.
+		if err := os.Chdir(getRepoRoot(ctx)); err != nil {
+			return fmt.Errorf("failed to change to the repository root: %w", err)
+		}
 
-		analysisInput, err := getAnalysisInput(mode)
+		run, err := getRunOptions(cliContext)
 		if err != nil {
 			return err
 		}
 
-		provider := getProvider(cliContext)
-		commitMessage, err := callAIAPI(analysisInput, provider)
+		cfg, err := loadGenerationConfig(cliContext)
 		if err != nil {
-			return fmt.Errorf("failed to generate commit message: %w", err)
+			return err
 		}
 
-		commitMessage = commitrules.CleanCommitMessage(commitMessage)
-		validateAndShowWarning(commitMessage)
+		settings := getGenerationSettings(cliContext, cfg)
+		analysisInput, paths, err := getAnalysisInput(ctx, mode, settings.analysisTokens())
+		if err != nil {
+			return interruptedOr(ctx, err)
+		}
+		if mode != "staged" && !run.printOnly && !run.dryRun {
+			showFiles("Will stage and commit", paths)
+		}
+		if mode == "all" && !run.printOnly {
+			warnPartlyStaged(ctx, paths)
+		}
 
-		if confirmCommit(commitMessage) {
-			return executeCommit(mode, commitMessage)
+		generate := func(guidance string) (string, error) {
+			message, err := callAIAPI(ctx, settings.prompt(analysisInput, guidance), settings)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate commit message: %w", err)
+			}
+			return settings.clean(message), nil
 		}
-		fmt.Println("Commit cancelled.")
-		return nil
+
+		var commitMessage string
+		if n := cfg.Candidates; n > 1 {
+			var picked bool
+			commitMessage, picked, err = selectCandidate(ctx, analysisInput, settings, n, run)
+			if err != nil {
+				return interruptedOr(ctx, err)
+			}
+			if run.interactive() && !picked {
+				fmt.Println("Commit cancelled.")
+				return nil
+			}
+		} else if commitMessage, err = generate(""); err != nil {
+			return err
+		}
+
+		return deliverMessage(ctx, mode, paths, commitMessage, generate, run)
 	}
 }
 
-func getAnalysisInput(mode string) (string, error) {
-	switch mode {
-	case "staged":
-		return analyzeStagedChanges()
-	case "all":
-		return analyzeAllChanges()
-	case "untracked":
-		return analyzeUntrackedFiles()
-	default:
-		return "", fmt.Errorf("%w: unknown mode: %s", ErrNoChangesFound, mode)
-	}
+// runOptions decides what happens with the generated message.
+type runOptions struct {
+	yes       bool
+	dryRun    bool
+	printOnly bool
+}
+
+// interactive reports whether the user is asked before committing.
+func (r runOptions) interactive() bool {
+	return !r.yes && !r.dryRun && !r.printOnly
 }
 
-func getProvider(cliContext *cli.Context) string {
-	provider := cliContext.String("provider")
-	if provider == "" {
-		provider = "claude"
+// getRunOptions reads --yes, --dry-run and --print. Without any of them, commitgen
+// prompts on a terminal, prints the message when used in a pipeline, and refuses
+// to guess when only stdin is redirected.
+func getRunOptions(cliContext *cli.Context) (runOptions, error) {
+	run := runOptions{
+		yes:       lookupContext(cliContext, "yes").Bool("yes"),
+		dryRun:    lookupContext(cliContext, "dry-run").Bool("dry-run"),
+		printOnly: lookupContext(cliContext, "print").Bool("print"),
+	}
+
+	set := 0
+	for _, enabled := range []bool{run.yes, run.dryRun, run.printOnly} {
+		if enabled {
+			set++
+		}
+	}
+	switch {
+	case set > 1:
+		return run, ErrConflictingModes
+	case set == 1 || isTerminal(os.Stdin):
+		return run, nil
+	case !isTerminal(os.Stdout):
+		run.printOnly = true
+		return run, nil
+	default:
+		return run, ErrNotInteractive
 	}
-	return provider
 }
 
-func validateAndShowWarning(commitMessage string) {
-	if err := commitrules.ValidateCommitMessage(commitMessage); err != nil {
-		fmt.Printf("Warning: %s\n", err)
+// isTerminal reports whether f is an interactive terminal. /dev/null is a
+// character device too, so it is ruled out explicitly.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
 	}
+	devNull, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, devNull)
 }
 
-func confirmCommit(commitMessage string) bool {
-	fmt.Printf("Generated commit message:\n\"%s\"\n\n", commitMessage)
-	fmt.Print("Do you want to use this commit message? [y/N] ")
+// deliverMessage prints, previews or commits the message according to run.
+// paths are the files that were analysed. Interactive runs review the message
+// first, including one picked from the candidate menu.
+func deliverMessage(ctx context.Context, mode string, paths []string, commitMessage string, regenerate regenerateFunc, run runOptions) error {
+	switch {
+	case run.printOnly:
+		validateAndShowWarning(commitMessage)
+		fmt.Println(commitMessage)
+		return nil
+	case run.dryRun:
+		showDryRun(mode, paths, commitMessage)
+		return nil
+	case run.yes:
+		validateAndShowWarning(commitMessage)
+	default:
+		reviewed, confirmed, err := reviewCommitMessage(ctx, commitMessage, regenerate)
+		if err != nil {
+			return interruptedOr(ctx, err)
+		}
+		if !confirmed {
+			fmt.Println("Commit cancelled.")
+			return nil
+		}
+		commitMessage = reviewed
+	}
 
-	var response string
-	_, err := fmt.Scanln(&response)
-	if err != nil {
-		response = ""
+	if err := executeCommit(ctx, mode, paths, commitMessage); err != nil {
+		return interruptedOr(ctx, err)
 	}
+	fmt.Println("Committed successfully!")
+	return nil
+}
 
-	confirmed := strings.ToLower(response) == "y" || strings.ToLower(response) == "yes"
-	if confirmed {
-		fmt.Println("Committed successfully!")
+// showDryRun lists the files the commit would include and the message it would use.
+func showDryRun(mode string, paths []string, commitMessage string) {
+	action := "Would commit"
+	if mode != "staged" {
+		action = "Would stage and commit"
 	}
-	return confirmed
+	showFiles(action, paths)
+	fmt.Printf("\nCommit message:\n%s\n", commitMessage)
+	validateAndShowWarning(commitMessage)
+	fmt.Println("\nDry run: nothing was staged or committed.")
 }
 
-func isGitRepo() bool {
-	_, err := exec.Command("git", "rev-parse", "--git-dir").CombinedOutput()
-	return err == nil
+// showFiles prints "<action> N file(s):" followed by the paths.
+func showFiles(action string, paths []string) {
+	fmt.Printf("%s %d file(s):\n", action, len(paths))
+	for _, path := range paths {
+		fmt.Printf("  %s\n", changeset.QuotePath(path))
+	}
 }
 
-// validateFilePath validates that a file path is safe to use.
-func validateFilePath(path string) bool {
-	// Check for path traversal attempts
-	if strings.Contains(path, "..") {
-		return false
+// interruptedOr replaces err with ErrInterrupted when the user cancelled the run.
+func interruptedOr(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return ErrInterrupted
 	}
-	// Check for dangerous characters
-	if strings.ContainsAny(path, "&|;<>()$`\"'") {
-		return false
+	return err
+}
+
+// loadConfig reads the user config and the config of the current repository.
+func loadConfig(ctx context.Context) (*config.Config, error) {
+	cfg, err := config.Load(getRepoRoot(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	// Check for empty path
-	if path == "" {
-		return false
+	for _, warning := range cfg.Warnings {
+		printWarning("%s", warning)
 	}
-	return true
+	return cfg, nil
 }
 
-func analyzeStagedChanges() (string, error) {
-	// Get staged files
-	cmd := exec.Command("git", "diff", "--cached", "--name-only")
-	output, err := cmd.Output()
+// getRepoRoot returns the top-level directory of the current repository, or "" outside one.
+func getRepoRoot(ctx context.Context) string {
+	output, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
 	if err != nil {
-		return "", fmt.Errorf("failed to get staged files: %w", err)
+		return ""
 	}
+	return strings.TrimSpace(string(output))
+}
 
-	stagedFiles := strings.TrimSpace(string(output))
-	if stagedFiles == "" {
-		return "", ErrNoStagedFiles
+// loadGenerationConfig loads the configuration, applies the command line flags
+// and sets up the configured providers and commit rules.
+func loadGenerationConfig(cliContext *cli.Context) (*config.Config, error) {
+	cfg, err := loadConfig(cliContext.Context)
+	if err != nil {
+		return nil, err
 	}
+	applyFlags(cliContext, cfg)
+	registerConfiguredProviders(cfg)
+	applyRules(cliContext.Context, cfg)
+	return cfg, nil
+}
 
-	var analysisInput strings.Builder
-	analysisInput.WriteString("=== STAGED CHANGES ANALYSIS ===\n")
-	files := strings.Split(stagedFiles, "\n")
-	analysisInput.WriteString(fmt.Sprintf("Files changed: %d\n", len(files)))
-	analysisInput.WriteString(fmt.Sprintf("Files: %s\n\n", strings.Join(files, " ")))
-
-	// Get diff stats
-	cmd = exec.Command("git", "diff", "--cached", "--stat")
-	output, _ = cmd.Output()
-	analysisInput.WriteString("=== DIFF ===\n")
-	analysisInput.Write(output)
-	analysisInput.WriteString("\n=== DETAILED CHANGES ===\n")
+// applyFlags overrides the loaded configuration with flags given on the command
+// line, the last and strongest layer.
+func applyFlags(cliContext *cli.Context, cfg *config.Config) {
+	if ctx := lookupContext(cliContext, "provider"); ctx.IsSet("provider") {
+		cfg.Provider = ctx.String("provider")
+	}
+	if ctx := lookupContext(cliContext, "model"); ctx.IsSet("model") {
+		cfg.Model = ctx.String("model")
+	}
+	if ctx := lookupContext(cliContext, "base-url"); ctx.IsSet("base-url") {
+		cfg.BaseURL = ctx.String("base-url")
+	}
+	if ctx := lookupContext(cliContext, "temperature"); ctx.IsSet("temperature") {
+		temperature := ctx.Float64("temperature")
+		cfg.Temperature = &temperature
+	}
+	if ctx := lookupContext(cliContext, "max-tokens"); ctx.IsSet("max-tokens") {
+		cfg.MaxTokens = ctx.Int("max-tokens")
+	}
+	if ctx := lookupContext(cliContext, "timeout"); ctx.IsSet("timeout") {
+		cfg.Timeout = ctx.Duration("timeout")
+	}
+	if ctx := lookupContext(cliContext, "retries"); ctx.IsSet("retries") {
+		cfg.Retries = ctx.Int("retries")
+	}
+	if ctx := lookupContext(cliContext, "retry-backoff"); ctx.IsSet("retry-backoff") {
+		cfg.RetryBackoff = ctx.Duration("retry-backoff")
+	}
+	if ctx := lookupContext(cliContext, "candidates"); ctx.IsSet("candidates") {
+		cfg.Candidates = ctx.Int("candidates")
+	}
+	if ctx := lookupContext(cliContext, "body"); ctx.IsSet("body") {
+		cfg.Body = ctx.Bool("body")
+	}
+}
 
-	// Get detailed diff for each file
-	for _, file := range files {
-		if !validateFilePath(file) {
-			continue
-		}
-		if _, err := os.Stat(file); err == nil {
-			analysisInput.WriteString(fmt.Sprintf("\n--- %s ---\n", file))
-			//nolint:gosec // G204: file path is validated by validateFilePath()
-			cmd = exec.Command("git", "diff", "--cached", "--unified=3", "--", file)
-			output, _ := cmd.Output()
-			if len(output) > 2000 {
-				output = output[:2000]
+// applyRules makes the configured types, scopes and limits the ones commitrules
+// prompts with and validates against. A commitlint config in the repository
+// overrides the rules it defines.
+func applyRules(ctx context.Context, cfg *config.Config) {
+	rules := commitrules.NewRuleSet()
+	if len(cfg.Rules.Types) > 0 {
+		rules.Types = make(map[string]commitrules.CommitRule, len(cfg.Rules.Types))
+		for _, t := range cfg.Rules.Types {
+			// A bare built-in name such as "feat" keeps its description and examples.
+			rule := commitrules.CommitRules[t.Name]
+			rule.Type = t.Name
+			if t.Description != "" {
+				rule.Description = t.Description
 			}
-			analysisInput.Write(output)
+			if len(t.Examples) > 0 {
+				rule.Examples = t.Examples
+			}
+			rules.Types[t.Name] = rule
 		}
 	}
+	rules.Scopes = cfg.Rules.Scopes
+	rules.HeaderMaxLength = cfg.Rules.HeaderMaxLength
+	rules.HeaderWarnLength = cfg.Rules.HeaderWarnLength
+	rules.BodyMaxLineLength = cfg.Rules.BodyMaxLineLength
 
-	return analysisInput.String(), nil
+	if root := getRepoRoot(ctx); root != "" && cfg.Rules.Commitlint {
+		applyCommitlint(root, rules)
+	}
+	commitrules.Default = rules
 }
 
-func analyzeAllChanges() (string, error) {
-	modifiedFiles, untrackedFiles, err := getModifiedAndUntrackedFiles()
+// applyCommitlint maps the repository's commitlint rules onto rules. A broken
+// commitlint config is reported but does not stop commitgen.
+func applyCommitlint(repoRoot string, rules *commitrules.RuleSet) {
+	lintConfig, warnings, err := commitlint.Find(repoRoot)
 	if err != nil {
-		return "", err
+		printWarning("ignoring commitlint config: %v", err)
+		return
 	}
-
-	if modifiedFiles == "" && untrackedFiles == "" {
-		return "", ErrNoChangesFound
+	if lintConfig != nil {
+		warnings = append(warnings, lintConfig.Apply(rules)...)
 	}
-
-	var analysisInput strings.Builder
-	analysisInput.WriteString("=== ALL CHANGES ANALYSIS ===\n")
-
-	if modifiedFiles != "" {
-		addModifiedFilesToAnalysis(&analysisInput, modifiedFiles)
+	for _, warning := range warnings {
+		printWarning("%s", warning)
 	}
+}
 
-	if untrackedFiles != "" {
-		addUntrackedFilesToAnalysis(&analysisInput, untrackedFiles)
+// registerConfiguredProviders makes the command providers from the config selectable by name.
+func registerConfiguredProviders(cfg *config.Config) {
+	for name, p := range cfg.Providers {
+		provider.Default.Register(name, provider.CommandFactory(provider.CommandSpec{
+			Argv:    p.Command,
+			Input:   provider.InputMode(p.Input),
+			Extract: p.Extract,
+		}))
 	}
-
-	return analysisInput.String(), nil
 }
 
-func getModifiedAndUntrackedFiles() (string, string, error) {
-	cmd := exec.Command("git", "diff", "--name-only")
-	modifiedOutput, err := cmd.Output()
+// getAnalysisInput describes the changes for mode in about tokens, shortening
+// file diffs and contents as needed. It also returns the analysed paths,
+// relative to the repository root; those are the files the commit stages.
+func getAnalysisInput(ctx context.Context, mode string, tokens int) (string, []string, error) {
+	set, err := changeset.Collect(ctx, changeset.Mode(mode))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get modified files: %w", err)
+		return "", nil, fmt.Errorf("failed to analyze changes: %w", err)
+	}
+	if len(set.Files) == 0 {
+		switch set.Mode {
+		case changeset.ModeStaged:
+			return "", nil, ErrNoStagedFiles
+		case changeset.ModeUntracked:
+			return "", nil, ErrNoUntrackedFiles
+		default:
+			return "", nil, ErrNoChangesFound
+		}
 	}
+	return changeset.Render(set, tokens), set.Paths(), nil
+}
 
-	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard")
-	untrackedOutput, err := cmd.Output()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to get untracked files: %w", err)
+type generationSettings struct {
+	providers []string
+	options   provider.Options
+	timeout   time.Duration
+	retry     provider.RetryPolicy
+	body      bool
+	// promptTokens is the budget for the whole prompt.
+	promptTokens int
+}
+
+// providerOptions returns the options for the i-th provider in the fallback
+// list. The model, base URL and API key are meant for the first provider, so
+// fallbacks use their own defaults instead of, say, sending an Anthropic key
+// to an OpenAI-compatible gateway.
+func (s generationSettings) providerOptions(i int) provider.Options {
+	opts := s.options
+	if i > 0 {
+		opts.Model, opts.BaseURL, opts.APIKey = "", "", ""
 	}
+	return opts
+}
+
+// minAnalysisTokens keeps some room for the change even when the budget is
+// mostly taken by the prompt instructions.
+const minAnalysisTokens = 500
 
-	return strings.TrimSpace(string(modifiedOutput)), strings.TrimSpace(string(untrackedOutput)), nil
+// analysisTokens returns the part of the prompt budget left for the change
+// description once the instructions are accounted for.
+func (s generationSettings) analysisTokens() int {
+	return max(s.promptTokens-budget.EstimateTokens(s.prompt("", "")), minAnalysisTokens)
 }
 
-func addModifiedFilesToAnalysis(analysisInput *strings.Builder, modifiedFiles string) {
-	files := strings.Split(modifiedFiles, "\n")
-	fmt.Fprintf(analysisInput, "Modified files: %d\n", len(files))
-	analysisInput.WriteString("=== MODIFIED FILES ===\n")
-	fmt.Fprintf(analysisInput, "%s\n\n", strings.Join(files, " "))
-	analysisInput.WriteString("=== MODIFICATIONS ===\n")
+// prompt builds the provider prompt for the configured message style.
+func (s generationSettings) prompt(analysisInput, guidance string) string {
+	return commitrules.BuildPrompt(analysisInput, commitrules.PromptOptions{Guidance: guidance, Body: s.body})
+}
 
-	for _, file := range files {
-		if !validateFilePath(file) {
-			continue
-		}
-		if _, err := os.Stat(file); err == nil {
-			addFileDiffToAnalysis(analysisInput, file)
-		}
+// clean normalises a provider response, keeping the body and footers in body mode.
+func (s generationSettings) clean(message string) string {
+	if s.body {
+		return commitrules.CleanCommitMessageWithBody(message)
 	}
+	return commitrules.CleanCommitMessage(message)
 }
 
-func addUntrackedFilesToAnalysis(analysisInput *strings.Builder, untrackedFiles string) {
-	files := strings.Split(untrackedFiles, "\n")
-	analysisInput.WriteString("\n=== UNTRACKED FILES ===\n")
-	fmt.Fprintf(analysisInput, "%s\n\n", strings.Join(files, " "))
-	analysisInput.WriteString("=== FILE CONTENTS ===\n")
+func getGenerationSettings(cliContext *cli.Context, cfg *config.Config) generationSettings {
+	settings := generationSettings{
+		providers: splitProviders(cfg.Provider),
+		options:   getProviderOptions(cliContext, cfg),
+		timeout:   cfg.Timeout,
+		retry: provider.RetryPolicy{
+			Retries:    cfg.Retries,
+			Backoff:    cfg.RetryBackoff,
+			MaxBackoff: provider.DefaultMaxBackoff,
+		},
+		body: cfg.Body,
+	}
+	settings.promptTokens = promptTokens(cfg, settings)
+	return settings
+}
 
-	for _, file := range files {
-		if !validateFilePath(file) {
-			continue
+// promptTokens returns the prompt budget for the providers. Each provider's
+// budget is its analysis.providerBudgets entry, else analysis.tokenBudget, else
+// the provider's own preference. With several providers the smallest wins, so
+// a fallback provider is never sent a prompt it cannot take.
+func promptTokens(cfg *config.Config, settings generationSettings) int {
+	smallest := 0
+	for i, name := range settings.providers {
+		tokens := cfg.Analysis.ProviderBudgets[name]
+		if tokens <= 0 {
+			tokens = cfg.Analysis.TokenBudget
 		}
-		if _, err := os.Stat(file); err == nil {
-			addFileContentToAnalysis(analysisInput, file)
+		if tokens <= 0 {
+			// Providers that cannot be built are skipped later anyway.
+			if p, err := provider.New(name, settings.providerOptions(i)); err == nil {
+				tokens = p.Capabilities().PromptTokens
+			}
+		}
+		if tokens <= 0 {
+			tokens = budget.DefaultTokens
+		}
+		if smallest == 0 || tokens < smallest {
+			smallest = tokens
 		}
 	}
+	if smallest == 0 {
+		return budget.DefaultTokens
+	}
+	return smallest
 }
 
-func addFileDiffToAnalysis(analysisInput *strings.Builder, file string) {
-	fmt.Fprintf(analysisInput, "\n--- %s ---\n", file)
-	cmd := exec.Command("git", "diff", "--unified=3", file)
-	output, _ := cmd.Output()
-	if len(output) > 2000 {
-		output = output[:2000]
+// splitProviders turns "claude, ollama,gemini" into a fallback list.
+func splitProviders(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
 	}
-	analysisInput.Write(output)
+	return names
 }
 
-func addFileContentToAnalysis(analysisInput *strings.Builder, file string) {
-	fmt.Fprintf(analysisInput, "\n--- %s (new) ---\n", file)
-	//nolint:gosec // G304: file path is validated by validateFilePath()
-	content, _ := os.ReadFile(file)
-	if len(content) > 2000 {
-		content = content[:2000]
+func getProviderOptions(cliContext *cli.Context, cfg *config.Config) provider.Options {
+	opts := provider.Options{
+		Model:       cfg.Model,
+		BaseURL:     cfg.BaseURL,
+		APIKey:      lookupString(cliContext, "api-key"),
+		MaxTokens:   cfg.MaxTokens,
+		Temperature: cfg.Temperature,
+	}
+	if lookupContext(cliContext, "verbose").Bool("verbose") {
+		opts.Verbose = os.Stderr
 	}
-	analysisInput.Write(content)
+	return opts
 }
 
-func analyzeUntrackedFiles() (string, error) {
-	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get untracked files: %w", err)
+// lookupContext returns the closest command context that set the flag, so
+// "commitgen --provider gemini commit staged" is not shadowed by the subcommand default.
+func lookupContext(cliContext *cli.Context, name string) *cli.Context {
+	for _, ctx := range cliContext.Lineage() {
+		if ctx.IsSet(name) {
+			return ctx
+		}
 	}
+	return cliContext
+}
+
+func lookupString(cliContext *cli.Context, name string) string {
+	return lookupContext(cliContext, name).String(name)
+}
+
+// stdinLines is shared so input typed ahead of a prompt is not lost between reads.
+var stdinLines = bufio.NewReader(os.Stdin)
 
-	untrackedFiles := strings.TrimSpace(string(output))
-	if untrackedFiles == "" {
-		return "", ErrNoUntrackedFiles
+// readLine reads one line from stdin, returning early if ctx is cancelled.
+func readLine(ctx context.Context) (string, error) {
+	type lineResult struct {
+		line string
+		err  error
 	}
 
-	var analysisInput strings.Builder
-	analysisInput.WriteString("=== UNTRACKED FILES ANALYSIS ===\n")
-	files := strings.Split(untrackedFiles, "\n")
-	analysisInput.WriteString(fmt.Sprintf("Files: %d\n", len(files)))
-	analysisInput.WriteString(fmt.Sprintf("%s\n\n", strings.Join(files, " ")))
-	analysisInput.WriteString("=== FILE CONTENTS ===\n")
+	done := make(chan lineResult, 1)
+	go func() {
+		line, err := stdinLines.ReadString('\n')
+		if err != nil && line != "" {
+			err = nil
+		}
+		done <- lineResult{line: line, err: err}
+	}()
 
-	for _, file := range files {
-		if !validateFilePath(file) {
-			continue
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case result := <-done:
+		return result.line, result.err
+	}
+}
+
+func isGitRepo() bool {
+	_, err := exec.Command("git", "rev-parse", "--git-dir").CombinedOutput()
+	return err == nil
+}
+
+func callAIAPI(ctx context.Context, prompt string, settings generationSettings) (string, error) {
+	chain, err := buildProviderChain(settings)
+	if err != nil {
+		return "", err
+	}
+
+	return runProvider(ctx, chain, prompt)
+}
+
+// runProvider asks the chain for a message and turns cancellation and timeouts into CLI errors.
+func runProvider(ctx context.Context, chain *provider.Chain, prompt string) (string, error) {
+	result, err := chain.Generate(ctx, prompt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ErrInterrupted
 		}
-		if _, err := os.Stat(file); err == nil {
-			analysisInput.WriteString(fmt.Sprintf("\n--- %s ---\n", file))
-			//nolint:gosec // G304: file path is validated by validateFilePath()
-			content, _ := os.ReadFile(file)
-			if len(content) > 2000 {
-				content = content[:2000]
-			}
-			analysisInput.Write(content)
+		if errors.Is(err, ErrProviderTimeout) {
+			return "", fmt.Errorf("%w (raise --timeout)", err)
 		}
+		return "", fmt.Errorf("provider %s: %w", chain.Name(), err)
 	}
 
-	return analysisInput.String(), nil
+	return result.Text, nil
 }
 
-func callAIAPI(analysisInput, provider string) (string, error) {
-	prompt := commitrules.GetPrompt(analysisInput)
+// buildProviderChain resolves every requested provider. A provider that cannot be
+// built (unknown name, missing API key) is skipped as long as another one remains.
+func buildProviderChain(settings generationSettings) (*provider.Chain, error) {
+	providers := make([]provider.Provider, 0, len(settings.providers))
+	var buildErrs []error
+	for i, name := range settings.providers {
+		aiProvider, err := provider.New(name, settings.providerOptions(i))
+		if err != nil {
+			buildErrs = append(buildErrs, err)
+			continue
+		}
+		providers = append(providers, aiProvider)
+	}
 
-	// Execute the provider command directly
-	cmd := exec.Command(provider)
+	if len(providers) == 0 {
+		if len(buildErrs) == 0 {
+			buildErrs = append(buildErrs, ErrUnsupportedProvider)
+		}
+		return nil, fmt.Errorf("failed to resolve provider: %w", errors.Join(buildErrs...))
+	}
+	for _, err := range buildErrs {
+		printWarning("skipping provider: %v", err)
+	}
 
-	cmd.Stdin = strings.NewReader(prompt)
+	chain := provider.NewChain(providers, settings.retry, settings.timeout)
+	chain.Notify = printWarning
+	return chain, nil
+}
 
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to call %s API: %w", provider, err)
+// stagePaths adds paths, relative to the repository root, to the index. They
+// are passed NUL-separated on stdin and taken literally, so names with spaces,
+// newlines or glob characters stage exactly that file.
+func stagePaths(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
 	}
+	cmd := exec.CommandContext(ctx, "git", "add", "--pathspec-from-file=-", "--pathspec-file-nul")
+	cmd.Dir = getRepoRoot(ctx)
+	cmd.Env = append(os.Environ(), "GIT_LITERAL_PATHSPECS=1")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stage changes: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
 
-	return strings.TrimSpace(string(output)), nil
+// warnPartlyStaged lists the paths that also have staged changes. The whole
+// working-tree version of each path is committed, but the analysis only
+// covered the unstaged part of it.
+func warnPartlyStaged(ctx context.Context, paths []string) {
+	partly, err := partlyStaged(ctx, paths)
+	if err != nil {
+		printWarning("%v", err)
+		return
+	}
+	if len(partly) == 0 {
+		return
+	}
+	quoted := make([]string, len(partly))
+	for i, path := range partly {
+		quoted[i] = changeset.QuotePath(path)
+	}
+	printWarning("%d file(s) also have staged changes that will be committed but were not analysed:\n  %s",
+		len(partly), strings.Join(quoted, "\n  "))
 }
 
+// partlyStaged returns the paths that have staged changes.
+func partlyStaged(ctx context.Context, paths []string) ([]string, error) {
+	output, err := exec.CommandContext(ctx, "git", "diff", "--cached", "--name-only", "--no-renames", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for staged changes: %w", err)
+	}
+	staged := map[string]bool{}
+	for _, path := range strings.Split(string(output), "\x00") {
+		staged[path] = true
+	}
+	var partly []string
+	for _, path := range paths {
+		if staged[path] {
+			partly = append(partly, path)
+		}
+	}
+	return partly, nil
+}
 
-func executeCommit(mode, commitMessage string) error {
-	var cmd *exec.Cmd
+// printWarning reports a recoverable problem on stderr.
+func printWarning(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
 
-	switch mode {
-	case "staged":
-		cmd = exec.Command("git", "commit", "-m", commitMessage)
-	case "all", "untracked":
-		// First stage all changes
-		if err := exec.Command("git", "add", ".").Run(); err != nil {
-			return fmt.Errorf("failed to stage changes: %w", err)
+// executeCommit commits the message. In the all and untracked modes it stages
+// exactly the analysed paths and commits only those, so files that appeared
+// since, that the model never saw, or that were staged earlier stay out of
+// the commit.
+func executeCommit(ctx context.Context, mode string, paths []string, commitMessage string) error {
+	// -F keeps the body and footers intact; -m would need one flag per paragraph.
+	args := []string{"commit", "-F", "-"}
+	if mode == "all" || mode == "untracked" {
+		if err := stagePaths(ctx, paths); err != nil {
+			return err
 		}
-		cmd = exec.Command("git", "commit", "-m", commitMessage)
+		// stdin carries the message, so the paths go through a file.
+		pathspec, err := writePathspecFile(paths)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(pathspec) }()
+		args = append(args, "--only", "--pathspec-from-file="+pathspec, "--pathspec-file-nul")
 	}
 
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = getRepoRoot(ctx)
+	cmd.Env = append(os.Environ(), "GIT_LITERAL_PATHSPECS=1")
+	cmd.Stdin = strings.NewReader(commitMessage + "\n")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+		return fmt.Errorf("failed to commit: %w: %s", err, strings.TrimSpace(stderr.String()))
 	}
 	return nil
 }
 
+// writePathspecFile writes paths NUL-separated to a temporary file and returns
+// its name.
+func writePathspecFile(paths []string) (string, error) {
+	file, err := os.CreateTemp("", "commitgen-pathspec-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write pathspec file: %w", err)
+	}
+	_, err = file.WriteString(strings.Join(paths, "\x00") + "\x00")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write pathspec file: %w", err)
+	}
+	return file.Name(), nil
+}
+
 func installBinary(c *cli.Context) error {
 	exePath, err := os.Executable()
 	if err != nil {
@@ -462,10 +893,7 @@ func installBinary(c *cli.Context) error {
 	}
 
 	// Copy binary to install path
-	if !validateFilePath(exePath) {
-		return fmt.Errorf("%w: invalid executable path: %s", ErrPermissionDenied, exePath)
-	}
-	//nolint:gosec // G304: exePath is validated by validateFilePath()
+	//nolint:gosec // G304: exePath is this program's own executable
 	source, err := os.Open(exePath)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
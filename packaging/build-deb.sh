@@ -25,7 +25,7 @@ sed -i "s/Version: .*/Version: ${VERSION}/" ${BUILD_DIR}/${PACKAGE_NAME}/DEBIAN/
 
 # Build binary for Linux amd64
 echo "Building binary..."
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags="-w -s" -o ${BUILD_DIR}/${PACKAGE_NAME}/opt/${PACKAGE_NAME}/bin/${PACKAGE_NAME} main.go
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags="-w -s" -o ${BUILD_DIR}/${PACKAGE_NAME}/opt/${PACKAGE_NAME}/bin/${PACKAGE_NAME} .
 
 # Calculate installed size
 INSTALLED_SIZE=$(du -s ${BUILD_DIR}/${PACKAGE_NAME}/opt | cut -f1)
@@ -0,0 +1,202 @@
+// Package budget fits file diffs and contents into the token budget of a prompt.
+package budget
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultTokens is the prompt budget used when neither the configuration nor
+// the provider sets one.
+const DefaultTokens = 8000
+
+// bytesPerToken is a rough average for source code and English text. It errs
+// on the side of overestimating, which keeps prompts inside the budget.
+const bytesPerToken = 4
+
+// markerTokens is kept free for the "more lines omitted" line.
+const markerTokens = 8
+
+// Importance weights. A file's share of the budget is proportional to its weight.
+const (
+	WeightLow    = 1
+	WeightNormal = 4
+)
+
+// lockFiles are dependency lock files, which are long and say little about intent.
+var lockFiles = map[string]bool{
+	"go.sum": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"Cargo.lock": true, "poetry.lock": true, "composer.lock": true, "Gemfile.lock": true,
+	"Pipfile.lock": true, "bun.lockb": true, "flake.lock": true,
+}
+
+// EstimateTokens approximates the number of tokens in text.
+func EstimateTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// Section is one file's part of the prompt.
+type Section struct {
+	// Path is the file the section describes; it decides the weight.
+	Path string
+	// Header introduces the section, e.g. "--- main.go ---". It is always kept.
+	Header string
+	// Body is a unified diff or the file content.
+	Body string
+	// Weight overrides Weight(Path) when positive, e.g. for a file whose
+	// content marks it as generated.
+	Weight int
+}
+
+func (s Section) weight() int {
+	if s.Weight > 0 {
+		return s.Weight
+	}
+	return Weight(s.Path)
+}
+
+// Weight returns how much of the budget a file deserves. Lock files, vendored
+// and generated code get little, so they do not crowd out the real change.
+func Weight(file string) int {
+	base := path.Base(file)
+	switch {
+	case lockFiles[base],
+		strings.HasPrefix(file, "vendor/"), strings.Contains(file, "/vendor/"),
+		strings.HasPrefix(file, "node_modules/"), strings.Contains(file, "/node_modules/"),
+		strings.HasSuffix(base, ".min.js"), strings.HasSuffix(base, ".min.css"), strings.HasSuffix(base, ".map"),
+		strings.HasSuffix(base, ".pb.go"), strings.HasSuffix(base, "_gen.go"), strings.HasPrefix(base, "zz_generated"),
+		strings.HasSuffix(base, ".svg"):
+		return WeightLow
+	default:
+		return WeightNormal
+	}
+}
+
+// Fit returns the section bodies shortened to fit tokens, including the
+// headers. Each body gets a share of the budget in proportion to its weight;
+// bodies that need less than their share leave the rest to the others. Diffs
+// are cut between hunks and other text between lines, and a "more lines
+// omitted" line replaces whatever does not fit.
+func Fit(sections []Section, tokens int) []string {
+	available := tokens
+	for _, section := range sections {
+		available -= EstimateTokens(section.Header)
+	}
+	allocation := allocate(sections, available)
+
+	bodies := make([]string, len(sections))
+	for i, section := range sections {
+		bodies[i] = Trim(section.Body, allocation[i])
+	}
+	return bodies
+}
+
+// allocate splits tokens between the sections by weight, giving any section
+// that needs less than its share exactly what it needs and sharing the rest
+// again among the others.
+func allocate(sections []Section, tokens int) []int {
+	allocation := make([]int, len(sections))
+	needs := make([]int, len(sections))
+	var open []int
+	for i, section := range sections {
+		if needs[i] = EstimateTokens(section.Body); needs[i] > 0 {
+			open = append(open, i)
+		}
+	}
+
+	remaining := max(tokens, 0)
+	for len(open) > 0 {
+		totalWeight := 0
+		for _, i := range open {
+			totalWeight += sections[i].weight()
+		}
+
+		var unsatisfied []int
+		for _, i := range open {
+			if needs[i] <= remaining*sections[i].weight()/totalWeight {
+				allocation[i] = needs[i]
+				remaining -= needs[i]
+			} else {
+				unsatisfied = append(unsatisfied, i)
+			}
+		}
+		if len(unsatisfied) == len(open) {
+			for _, i := range open {
+				allocation[i] = remaining * sections[i].weight() / totalWeight
+			}
+			break
+		}
+		open = unsatisfied
+	}
+	return allocation
+}
+
+// Trim shortens body to about tokens. A diff keeps its file header and whole
+// hunks; only a first hunk that is too large on its own is cut between lines.
+// Other text is cut between lines. Lines are never split.
+func Trim(body string, tokens int) string {
+	if EstimateTokens(body) <= tokens {
+		return body
+	}
+
+	limit := (tokens - markerTokens) * bytesPerToken
+	lines := strings.SplitAfter(strings.TrimSuffix(body, "\n"), "\n")
+	var kept strings.Builder
+	keptLines, keptHunks := 0, 0
+	for _, block := range blocks(lines) {
+		size := 0
+		for _, line := range block {
+			size += len(line)
+		}
+		if kept.Len()+size <= limit {
+			for _, line := range block {
+				kept.WriteString(line)
+			}
+			keptLines += len(block)
+			if strings.HasPrefix(block[0], "@@ ") {
+				keptHunks++
+			}
+			continue
+		}
+		// The start of a hunk that is too large on its own is still worth a look.
+		if keptHunks == 0 {
+			for _, line := range block {
+				if kept.Len()+len(line) > limit {
+					break
+				}
+				kept.WriteString(line)
+				keptLines++
+			}
+		}
+		break
+	}
+
+	text := kept.String()
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + fmt.Sprintf("[... %d more lines omitted]\n", len(lines)-keptLines)
+}
+
+// blocks groups the lines of a diff into its file header and its hunks. Text
+// that is not a diff is one block per line.
+func blocks(lines []string) [][]string {
+	isDiff := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "@@ ") {
+			isDiff = true
+			break
+		}
+	}
+
+	var result [][]string
+	for _, line := range lines {
+		if !isDiff || len(result) == 0 || strings.HasPrefix(line, "@@ ") {
+			result = append(result, []string{line})
+			continue
+		}
+		result[len(result)-1] = append(result[len(result)-1], line)
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package budget
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// diff returns a unified diff with the given number of hunks of n lines each.
+func diff(hunks, n int) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n")
+	for h := 0; h < hunks; h++ {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h*100, n, h*100, n)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "+line %d of hunk %d\n", i, h)
+		}
+	}
+	return b.String()
+}
+
+func TestTrimKeepsWholeHunks(t *testing.T) {
+	body := diff(3, 10)
+	got := Trim(body, EstimateTokens(body)/2)
+
+	if !strings.HasPrefix(got, "diff --git a/x.go b/x.go\n") {
+		t.Errorf("Trim dropped the diff header:\n%s", got)
+	}
+	if !strings.Contains(got, "+line 9 of hunk 0\n") || strings.Contains(got, "hunk 2") {
+		t.Errorf("Trim should keep hunk 0 whole and drop hunk 2:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "more lines omitted]\n") {
+		t.Errorf("Trim did not add the omitted marker:\n%s", got)
+	}
+	if Trim(body, EstimateTokens(body)) != body {
+		t.Error("Trim changed a body that fits")
+	}
+}
+
+func TestTrimCutsBetweenLines(t *testing.T) {
+	body := strings.Repeat("héllo wörld\n", 100)
+	got := Trim(body, 50)
+	omitted := strings.Count(body, "\n") - strings.Count(got, "héllo wörld\n")
+	if !strings.HasSuffix(got, fmt.Sprintf("[... %d more lines omitted]\n", omitted)) {
+		t.Errorf("Trim = %q, want whole lines and a marker for the %d omitted ones", got, omitted)
+	}
+	if EstimateTokens(got) > 50 {
+		t.Errorf("Trim used %d tokens, want at most 50", EstimateTokens(got))
+	}
+}
+
+func TestFitSharesTheBudget(t *testing.T) {
+	small := "+one line\n"
+	sections := []Section{
+		{Path: "main.go", Header: "--- main.go ---\n", Body: diff(20, 20)},
+		{Path: "go.sum", Header: "--- go.sum ---\n", Body: strings.Repeat("example.com/mod v1.0.0 h1:abc=\n", 200)},
+		{Path: "README.md", Header: "--- README.md ---\n", Body: small},
+	}
+	bodies := Fit(sections, 1000)
+
+	total := 0
+	for i, body := range bodies {
+		total += EstimateTokens(sections[i].Header) + EstimateTokens(body)
+	}
+	if total > 1000 {
+		t.Errorf("Fit used %d tokens, want at most 1000", total)
+	}
+	if bodies[2] != small {
+		t.Errorf("small file = %q, want it unchanged", bodies[2])
+	}
+	if EstimateTokens(bodies[0]) < 3*EstimateTokens(bodies[1]) {
+		t.Errorf("main.go got %d tokens and go.sum %d, want the source file favoured",
+			EstimateTokens(bodies[0]), EstimateTokens(bodies[1]))
+	}
+}
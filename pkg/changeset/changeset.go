@@ -0,0 +1,149 @@
+// Package changeset collects the changes commitgen describes to a provider
+// into one structured model, shared by every analysis mode.
+package changeset
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Define static errors for change collection.
+var (
+	ErrUnknownMode    = errors.New("unknown analysis mode")
+	ErrUnexpectedDiff = errors.New("unexpected git diff output")
+)
+
+// Mode selects which changes are collected.
+type Mode string
+
+// Analysis modes.
+const (
+	// ModeStaged collects the staged changes: the index against HEAD.
+	ModeStaged Mode = "staged"
+	// ModeAll collects unstaged changes to tracked files and untracked files.
+	ModeAll Mode = "all"
+	// ModeUntracked collects untracked files only.
+	ModeUntracked Mode = "untracked"
+)
+
+// Status is how a file changed.
+type Status string
+
+// File statuses. All but StatusUntracked come from the status letters of git diff --raw.
+const (
+	StatusAdded       Status = "added"
+	StatusModified    Status = "modified"
+	StatusDeleted     Status = "deleted"
+	StatusRenamed     Status = "renamed"
+	StatusCopied      Status = "copied"
+	StatusTypeChanged Status = "type changed"
+	StatusUnmerged    Status = "unmerged"
+	StatusUntracked   Status = "untracked"
+)
+
+// symlinkMode is the git file mode of a symbolic link.
+const symlinkMode = "120000"
+
+// Stats counts changed lines. Both are 0 for binary files.
+type Stats struct {
+	Added   int `json:"added"`
+	Deleted int `json:"deleted"`
+}
+
+// Hunk is one hunk of a unified diff. The content of an untracked file is a
+// single hunk that adds every line.
+type Hunk struct {
+	// Header is the "@@ -1,3 +1,4 @@ context" line.
+	Header   string `json:"header"`
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	// Lines keep their " ", "+" or "-" prefix.
+	Lines []string `json:"lines"`
+}
+
+// File is one changed file.
+type File struct {
+	Path string `json:"path"`
+	// OldPath is the source of a rename or copy.
+	OldPath string `json:"oldPath,omitempty"`
+	Status  Status `json:"status"`
+	// Similarity is the percentage of a renamed or copied file that is unchanged.
+	Similarity int    `json:"similarity,omitempty"`
+	OldMode    string `json:"oldMode,omitempty"`
+	NewMode    string `json:"newMode,omitempty"`
+	Binary     bool   `json:"binary,omitempty"`
+	Stats      Stats  `json:"stats"`
+	// Language is the file's language, e.g. "Go"; empty when unknown.
+	Language string `json:"language,omitempty"`
+	// Generated is true for lock files, vendored, minified and generated code.
+	Generated bool `json:"generated,omitempty"`
+	// Hunks is empty for deleted and binary files and for pure renames.
+	Hunks []Hunk `json:"hunks,omitempty"`
+}
+
+// ChangeSet is every change collected for one analysis mode.
+type ChangeSet struct {
+	Mode  Mode   `json:"mode"`
+	Files []File `json:"files"`
+}
+
+// Stats returns the line counts of all files.
+func (s *ChangeSet) Stats() Stats {
+	var total Stats
+	for _, file := range s.Files {
+		total.Added += file.Stats.Added
+		total.Deleted += file.Stats.Deleted
+	}
+	return total
+}
+
+// Paths returns every path the changes touch, including both sides of a
+// rename; these are the paths to stage to commit the changes.
+func (s *ChangeSet) Paths() []string {
+	var paths []string
+	for _, file := range s.Files {
+		if file.Status == StatusRenamed {
+			paths = append(paths, file.OldPath)
+		}
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+// Select returns the part of the change set whose files have one of paths.
+func (s *ChangeSet) Select(paths []string) *ChangeSet {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	selected := &ChangeSet{Mode: s.Mode}
+	for _, file := range s.Files {
+		if wanted[file.Path] {
+			selected.Files = append(selected.Files, file)
+		}
+	}
+	return selected
+}
+
+// ModeChanged reports whether an existing file changed mode, e.g. became executable.
+func (f File) ModeChanged() bool {
+	switch f.Status {
+	case StatusAdded, StatusDeleted, StatusUnmerged, StatusUntracked:
+		return false
+	default:
+		return f.OldMode != f.NewMode
+	}
+}
+
+// QuotePath returns path as is, or as a Go string literal when it contains
+// spaces, quotes or control characters. Non-ASCII text is kept readable.
+func QuotePath(path string) string {
+	if strings.ContainsFunc(path, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) || r == '"' }) {
+		return strconv.Quote(path)
+	}
+	return path
+}
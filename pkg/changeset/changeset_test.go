@@ -0,0 +1,286 @@
+package changeset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository with one commit and makes it the working
+// directory for the rest of the test.
+func initRepo(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(previous) })
+
+	git(t, "init", "-q")
+	git(t, "config", "user.email", "test@example.com")
+	git(t, "config", "user.name", "Test")
+	git(t, "commit", "-q", "--allow-empty", "-m", "chore: initial commit")
+	return dir
+}
+
+func git(t testing.TB, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func writeFile(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	output := ":100644 100644 fb69112 192b1b8 M\x00bin.dat\x00" +
+		":100644 100644 e8823e1 10adcaf R096\x00mv.txt\x00moved.txt\x00" +
+		":100644 100755 c1b0730 c1b0730 M\x00m.sh\x00" +
+		":100644 000000 96cc558 0000000 D\x00gone.txt\x00" +
+		"-\t-\tbin.dat\x00" +
+		"1\t0\t\x00mv.txt\x00moved.txt\x00" +
+		"0\t0\tm.sh\x00" +
+		"0\t40\tgone.txt\x00"
+
+	files, err := parseRaw(output)
+	if err != nil {
+		t.Fatalf("parseRaw returned error: %v", err)
+	}
+	want := []File{
+		{Status: StatusModified, Path: "bin.dat", OldMode: "100644", NewMode: "100644", Binary: true},
+		{Status: StatusRenamed, Path: "moved.txt", OldPath: "mv.txt", Similarity: 96, OldMode: "100644", NewMode: "100644", Stats: Stats{Added: 1}},
+		{Status: StatusModified, Path: "m.sh", OldMode: "100644", NewMode: "100755"},
+		{Status: StatusDeleted, Path: "gone.txt", OldMode: "100644", NewMode: "000000", Stats: Stats{Deleted: 40}},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("parseRaw = %+v, want %+v", files, want)
+	}
+
+	labels := []string{"modified, binary", "renamed from mv.txt, 96% similar, +1 -0", "modified, mode 100644 -> 100755", "deleted, 40 lines removed"}
+	for i, label := range labels {
+		if got := Label(files[i]); got != label {
+			t.Errorf("label %d = %q, want %q", i, got, label)
+		}
+	}
+
+	if _, err := parseRaw(":100644 M\x00a.go\x00"); err == nil {
+		t.Error("expected an error for a truncated raw record")
+	}
+}
+
+func TestParseHunks(t *testing.T) {
+	diff := "diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n" +
+		"@@ -1,2 +1,3 @@ package x\n a\n+b\n c\n" +
+		"@@ -10 +11 @@\n-d\n+e\n"
+	want := []Hunk{
+		{Header: "@@ -1,2 +1,3 @@ package x", OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 3, Lines: []string{" a", "+b", " c"}},
+		{Header: "@@ -10 +11 @@", OldStart: 10, OldLines: 1, NewStart: 11, NewLines: 1, Lines: []string{"-d", "+e"}},
+	}
+	if got := parseHunks(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHunks = %+v, want %+v", got, want)
+	}
+}
+
+func TestAttachHunksFollowsThePatchOrder(t *testing.T) {
+	files := []File{
+		{Path: "conflict.go", Status: StatusUnmerged},
+		{Path: "link", Status: StatusTypeChanged, OldMode: "120000", NewMode: "100644", Stats: Stats{Added: 1, Deleted: 1}},
+		{Path: "gone.go", Status: StatusDeleted, Stats: Stats{Deleted: 1}},
+		{Path: "main.go", Status: StatusModified, Stats: Stats{Added: 1}},
+	}
+	patch := "* Unmerged path conflict.go\n" +
+		"diff --git a/link b/link\ndeleted file mode 120000\n@@ -1 +0,0 @@\n-target\n" +
+		"diff --git a/link b/link\nnew file mode 100644\n@@ -0,0 +1 @@\n+text\n" +
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n@@ -1 +0,0 @@\n-package gone\n" +
+		"diff --git a/main.go b/main.go\n@@ -1 +1,2 @@\n package main\n+// main\n"
+
+	if err := attachHunks(files, splitPatch(patch)); err != nil {
+		t.Fatalf("attachHunks returned error: %v", err)
+	}
+	if files[0].Hunks != nil || files[2].Hunks != nil {
+		t.Errorf("unmerged and deleted files got hunks: %+v, %+v", files[0].Hunks, files[2].Hunks)
+	}
+	if got := renderHunks(files[1].Hunks); got != "@@ -0,0 +1 @@\n+text\n" {
+		t.Errorf("type change hunks = %q, want the new content", got)
+	}
+	if got := renderHunks(files[3].Hunks); got != "@@ -1 +1,2 @@\n package main\n+// main\n" {
+		t.Errorf("main.go hunks = %q", got)
+	}
+
+	if err := attachHunks(files, splitPatch(patch)[:2]); !errors.Is(err, ErrUnexpectedDiff) {
+		t.Errorf("attachHunks error = %v, want ErrUnexpectedDiff for a short patch", err)
+	}
+}
+
+func TestCollectStagedNamesEveryKindOfChange(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, "gone.txt"), "removed\n")
+	writeFile(t, filepath.Join(dir, "old.txt"), strings.Repeat("line\n", 20))
+	writeFile(t, filepath.Join(dir, "run.sh"), "echo\n")
+	git(t, "add", ".")
+	git(t, "commit", "-q", "-m", "chore: add files")
+	git(t, "rm", "-q", "gone.txt")
+	git(t, "mv", "old.txt", "new.txt")
+	git(t, "update-index", "--chmod=+x", "run.sh")
+	writeFile(t, filepath.Join(dir, "image.png"), "\x89PNG\x00\x01\x02")
+	writeFile(t, filepath.Join(dir, "api.pb.go"), "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n")
+	writeFile(t, filepath.Join(dir, "untracked.txt"), "not staged\n")
+	git(t, "add", "image.png", "api.pb.go")
+
+	set, err := Collect(context.Background(), ModeStaged)
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	labels := map[string]string{}
+	for _, file := range set.Files {
+		labels[file.Path] = Label(file)
+	}
+	want := map[string]string{
+		"api.pb.go": "added, generated, 2 lines",
+		"gone.txt":  "deleted, 1 line removed",
+		"image.png": "added, binary",
+		"new.txt":   "renamed from old.txt, 100% similar",
+		"run.sh":    "modified, mode 100644 -> 100755",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+	if got := strings.Join(set.Paths(), ","); got != "api.pb.go,gone.txt,image.png,old.txt,new.txt,run.sh" {
+		t.Errorf("Paths = %s, want both sides of the rename", got)
+	}
+	if set.Files[0].Language != "Go" || len(set.Files[0].Hunks) != 1 {
+		t.Errorf("api.pb.go = %+v, want a Go file with one hunk", set.Files[0])
+	}
+}
+
+func TestCollectAllIncludesUnstagedAndUntrackedFiles(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "gone.md"), "# Gone\n")
+	git(t, "add", ".")
+	git(t, "commit", "-q", "-m", "chore: add files")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {}\n")
+	if err := os.Remove(filepath.Join(dir, "gone.md")); err != nil {
+		t.Fatalf("failed to remove gone.md: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "docs/Design (v2).md"), "# Design\n\nText\n")
+
+	set, err := Collect(context.Background(), ModeAll)
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if got := strings.Join(set.Paths(), ","); got != "gone.md,main.go,docs/Design (v2).md" {
+		t.Errorf("Paths = %s", got)
+	}
+	if got := set.Stats(); got != (Stats{Added: 5, Deleted: 1}) {
+		t.Errorf("Stats = %+v, want +5 -1", got)
+	}
+
+	text := Render(set, 8000)
+	for _, want := range []string{
+		"=== ALL CHANGES ANALYSIS ===\nFiles changed: 3 (+5 -1)\n",
+		`Files: gone.md main.go "docs/Design (v2).md"`,
+		"Languages: Markdown, Go\n",
+		"\n--- gone.md (deleted, 1 line removed) ---\n",
+		"\n--- main.go (modified, +2 -0) ---\n@@ -1 +1,3 @@\n package main\n+\n+func main() {}\n",
+		"\n--- \"docs/Design (v2).md\" (untracked, 3 lines) ---\n@@ -0,0 +1,3 @@\n+# Design\n+\n+Text\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Render is missing %q:\n%s", want, text)
+		}
+	}
+
+	untracked, err := Collect(context.Background(), ModeUntracked)
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if len(untracked.Files) != 1 || untracked.Files[0].Status != StatusUntracked {
+		t.Errorf("untracked files = %+v, want only the new file", untracked.Files)
+	}
+	if _, err := Collect(context.Background(), "everything"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestCollectUntrackedDoesNotFollowSymlinks(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "id_rsa")
+	writeFile(t, secret, "PRIVATE KEY\n")
+	dir := initRepo(t)
+	if err := os.Symlink(secret, filepath.Join(dir, "key")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	set, err := Collect(context.Background(), ModeUntracked)
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	text := Render(set, 8000)
+	if strings.Contains(text, "PRIVATE KEY") {
+		t.Errorf("Render includes the symlink target's content:\n%s", text)
+	}
+	if want := "\n--- key (untracked, symlink, 1 line) ---\n@@ -0,0 +1,1 @@\n+" + secret + "\n"; !strings.Contains(text, want) {
+		t.Errorf("Render is missing %q:\n%s", want, text)
+	}
+}
+
+// BenchmarkCollectStaged compares collecting a 500-file refactor in one git
+// diff with running git diff once per file, as earlier versions did.
+func BenchmarkCollectStaged(b *testing.B) {
+	const files = 500
+	dir := initRepo(b)
+	for i := 0; i < files; i++ {
+		writeFile(b, filepath.Join(dir, fmt.Sprintf("pkg%d/file%d.go", i%20, i)), strings.Repeat("line\n", 50))
+	}
+	git(b, "add", ".")
+	git(b, "commit", "-q", "-m", "chore: add files")
+	for i := 0; i < files; i++ {
+		writeFile(b, filepath.Join(dir, fmt.Sprintf("pkg%d/file%d.go", i%20, i)), "changed\n"+strings.Repeat("line\n", 50))
+	}
+	git(b, "add", ".")
+	ctx := context.Background()
+
+	b.Run("single-pass", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			set, err := Collect(ctx, ModeStaged)
+			if err != nil || len(set.Files) != files {
+				b.Fatalf("Collect = %d files, %v", len(set.Files), err)
+			}
+		}
+	})
+	b.Run("per-file", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			output, err := exec.Command("git", "diff", "--cached", "--name-only", "-z").Output()
+			if err != nil {
+				b.Fatalf("git diff failed: %v", err)
+			}
+			for _, path := range strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00") {
+				if _, err := os.Stat(path); err != nil {
+					b.Fatalf("stat failed: %v", err)
+				}
+				diff, err := exec.Command("git", "diff", "--cached", "--unified=3", "--", path).Output()
+				if err != nil {
+					b.Fatalf("git diff failed: %v", err)
+				}
+				_ = parseHunks(string(diff))
+			}
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package changeset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// binarySniffBytes is how much of a file is checked for NUL bytes, as git does.
+const binarySniffBytes = 8000
+
+var statuses = map[byte]Status{
+	'A': StatusAdded,
+	'M': StatusModified,
+	'D': StatusDeleted,
+	'R': StatusRenamed,
+	'C': StatusCopied,
+	'T': StatusTypeChanged,
+	'U': StatusUnmerged,
+}
+
+// Collect gathers the changes for mode in the current directory, which must
+// be the repository root.
+func Collect(ctx context.Context, mode Mode) (*ChangeSet, error) {
+	set := &ChangeSet{Mode: mode}
+	switch mode {
+	case ModeStaged, ModeAll:
+		files, err := collectTracked(ctx, mode == ModeStaged)
+		if err != nil {
+			return nil, err
+		}
+		set.Files = files
+	case ModeUntracked:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, mode)
+	}
+
+	if mode != ModeStaged {
+		files, err := collectUntracked(ctx)
+		if err != nil {
+			return nil, err
+		}
+		set.Files = append(set.Files, files...)
+	}
+
+	for i := range set.Files {
+		file := &set.Files[i]
+		file.Language = Language(file.Path)
+		file.Generated = isGenerated(*file)
+	}
+	return set, ctx.Err()
+}
+
+// collectTracked lists the changes to tracked files, staged ones when cached
+// is true and unstaged ones otherwise, with their hunks. A single git diff
+// prints the file list and the whole patch, however many files changed.
+func collectTracked(ctx context.Context, cached bool) ([]File, error) {
+	args := []string{"diff"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	// --raw is --name-status with the file modes; --numstat adds line counts
+	// and marks binary files. With -z they end with an empty record, and the
+	// patch follows.
+	// The other options override user settings that would change the patch
+	// layout, such as diff.submodule=log.
+	args = append(args, "--find-renames", "--find-copies", "--no-color", "--no-ext-diff",
+		"--submodule=short", "--unified=3", "-z", "--raw", "--numstat", "--patch")
+	output, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	listing, patch, _ := strings.Cut(string(output), "\x00\x00")
+	files, err := parseRaw(listing)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachHunks(files, splitPatch(patch)); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// parseRaw reads the output of git diff -z --raw --numstat: one raw record per
+// file, then one numstat record per file in the same order.
+func parseRaw(output string) ([]File, error) {
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	var files []File
+	stat := 0
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "":
+			continue
+		case strings.HasPrefix(field, ":"):
+			// :<old mode> <new mode> <old sha> <new sha> <status>, then the path or
+			// the old and new paths.
+			raw := strings.Fields(field[1:])
+			if len(raw) != 5 || statuses[raw[4][0]] == "" {
+				return nil, fmt.Errorf("%w: %q", ErrUnexpectedDiff, field)
+			}
+			file := File{Status: statuses[raw[4][0]], OldMode: raw[0], NewMode: raw[1]}
+			if file.Status == StatusRenamed || file.Status == StatusCopied {
+				file.Similarity, _ = strconv.Atoi(raw[4][1:])
+				if i+2 >= len(fields) {
+					return nil, fmt.Errorf("%w: %q", ErrUnexpectedDiff, field)
+				}
+				file.OldPath = fields[i+1]
+				i++
+			}
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("%w: %q", ErrUnexpectedDiff, field)
+			}
+			file.Path = fields[i+1]
+			i++
+			files = append(files, file)
+		default:
+			// <added>\t<deleted>\t<path>, with an empty path followed by the old and
+			// new paths for renames and copies. Binary files count "-".
+			counts := strings.SplitN(field, "\t", 3)
+			if len(counts) != 3 || stat >= len(files) {
+				return nil, fmt.Errorf("%w: %q", ErrUnexpectedDiff, field)
+			}
+			if counts[2] == "" {
+				i += 2
+			}
+			file := &files[stat]
+			stat++
+			if counts[0] == "-" {
+				file.Binary = true
+				continue
+			}
+			file.Stats.Added, _ = strconv.Atoi(counts[0])
+			file.Stats.Deleted, _ = strconv.Atoi(counts[1])
+		}
+	}
+	return files, nil
+}
+
+// hasHunks reports whether the change has line changes worth showing. Deleted
+// and binary files are described by their status alone.
+func hasHunks(file File) bool {
+	return !file.Binary && file.Status != StatusDeleted && file.Stats.Added+file.Stats.Deleted > 0
+}
+
+// splitPatch splits a patch into one section per "diff --git" header.
+func splitPatch(patch string) []string {
+	var sections []string
+	var section strings.Builder
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if section.Len() > 0 {
+				sections = append(sections, section.String())
+			}
+			section.Reset()
+		case strings.HasPrefix(line, "* Unmerged path "):
+			// Unmerged paths get this line instead of a diff.
+			continue
+		}
+		section.WriteString(line)
+	}
+	if section.Len() > 0 {
+		sections = append(sections, section.String())
+	}
+	return sections
+}
+
+// attachHunks gives each file the hunks of its patch section. The patch lists
+// files in the same order as the raw records; a type change is printed as a
+// deletion followed by a creation, and an unmerged path has no section.
+func attachHunks(files []File, sections []string) error {
+	next := 0
+	for i := range files {
+		count := 1
+		switch files[i].Status {
+		case StatusTypeChanged:
+			count = 2
+		case StatusUnmerged:
+			count = 0
+		}
+		if next+count > len(sections) {
+			return fmt.Errorf("%w: %d patch sections for %d files", ErrUnexpectedDiff, len(sections), len(files))
+		}
+		next += count
+		if count > 0 && hasHunks(files[i]) {
+			files[i].Hunks = parseHunks(sections[next-1])
+		}
+	}
+	return nil
+}
+
+// parseHunks splits a unified diff of one file into hunks, dropping the file
+// header lines before the first hunk.
+func parseHunks(diff string) []Hunk {
+	var hunks []Hunk
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "@@ ") {
+			hunk := Hunk{Header: line}
+			// @@ -<start>[,<lines>] +<start>[,<lines>] @@
+			if ranges := strings.Fields(line); len(ranges) >= 3 {
+				hunk.OldStart, hunk.OldLines = parseRange(strings.TrimPrefix(ranges[1], "-"))
+				hunk.NewStart, hunk.NewLines = parseRange(strings.TrimPrefix(ranges[2], "+"))
+			}
+			hunks = append(hunks, hunk)
+			continue
+		}
+		if len(hunks) > 0 {
+			last := &hunks[len(hunks)-1]
+			last.Lines = append(last.Lines, line)
+		}
+	}
+	return hunks
+}
+
+// parseRange reads "<start>,<lines>"; a missing count means one line.
+func parseRange(text string) (start, lines int) {
+	startText, linesText, found := strings.Cut(text, ",")
+	start, _ = strconv.Atoi(startText)
+	lines = 1
+	if found {
+		lines, _ = strconv.Atoi(linesText)
+	}
+	return start, lines
+}
+
+// collectUntracked lists the untracked files that are not ignored, with their
+// content as a hunk that adds every line.
+func collectUntracked(ctx context.Context) ([]File, error) {
+	output, err := exec.CommandContext(ctx, "git", "ls-files", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get untracked files: %w", err)
+	}
+
+	var files []File
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path == "" {
+			continue
+		}
+		file := File{Path: path, Status: StatusUntracked}
+		content, err := readUntracked(&file)
+		switch {
+		case err != nil:
+		case bytes.IndexByte(content[:min(len(content), binarySniffBytes)], 0) >= 0:
+			file.Binary = true
+		case len(content) > 0:
+			file.Hunks = []Hunk{contentHunk(string(content))}
+			file.Stats.Added = file.Hunks[0].NewLines
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// readUntracked returns the content of an untracked file. A symlink is not
+// followed: like git, it is described by its target path, so a link to a file
+// outside the repository does not send that file to the provider.
+func readUntracked(file *File) ([]byte, error) {
+	info, err := os.Lstat(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		file.NewMode = symlinkMode
+		target, err := os.Readlink(file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+		return []byte(target), nil
+	}
+	//nolint:gosec // G304: path is a regular file git listed in the repository
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	return content, nil
+}
+
+// contentHunk turns the content of a new file into a hunk adding every line.
+func contentHunk(content string) Hunk {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	hunk := Hunk{
+		Header:   fmt.Sprintf("@@ -0,0 +1,%d @@", len(lines)),
+		NewStart: 1,
+		NewLines: len(lines),
+		Lines:    make([]string, len(lines)),
+	}
+	for i, line := range lines {
+		hunk.Lines[i] = "+" + line
+	}
+	return hunk
+}
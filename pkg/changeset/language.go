@@ -0,0 +1,64 @@
+package changeset
+
+import (
+	"path"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/budget"
+)
+
+// generatedLines is how many lines at the top of a file are searched for a
+// "generated" marker.
+const generatedLines = 10
+
+// languagesByName are files recognised by their whole name.
+var languagesByName = map[string]string{
+	"Dockerfile": "Dockerfile", "Makefile": "Makefile", "CMakeLists.txt": "CMake",
+	"go.mod": "Go", "go.sum": "Go", "Gemfile": "Ruby", "Rakefile": "Ruby",
+}
+
+// languagesByExtension maps lower-case file extensions to languages.
+var languagesByExtension = map[string]string{
+	".go": "Go", ".py": "Python", ".rb": "Ruby", ".rs": "Rust", ".java": "Java",
+	".kt": "Kotlin", ".kts": "Kotlin", ".scala": "Scala", ".swift": "Swift",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hpp": "C++",
+	".cs": "C#", ".php": "PHP", ".lua": "Lua", ".dart": "Dart", ".ex": "Elixir", ".exs": "Elixir",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".vue": "Vue", ".svelte": "Svelte",
+	".html": "HTML", ".htm": "HTML", ".css": "CSS", ".scss": "SCSS", ".sql": "SQL",
+	".sh": "Shell", ".bash": "Shell", ".zsh": "Shell", ".ps1": "PowerShell",
+	".md": "Markdown", ".markdown": "Markdown", ".rst": "reStructuredText", ".txt": "Text",
+	".yaml": "YAML", ".yml": "YAML", ".json": "JSON", ".toml": "TOML", ".xml": "XML",
+	".proto": "Protocol Buffers", ".tf": "Terraform", ".graphql": "GraphQL",
+}
+
+// Language returns the language of a file from its name, or "" when unknown.
+func Language(file string) string {
+	base := path.Base(file)
+	if language, ok := languagesByName[base]; ok {
+		return language
+	}
+	return languagesByExtension[strings.ToLower(path.Ext(base))]
+}
+
+// isGenerated reports whether a file is machine-written: a lock file, vendored
+// or minified code, or a file whose first lines say it is generated, such as
+// Go's "Code generated ... DO NOT EDIT." header.
+func isGenerated(file File) bool {
+	if budget.Weight(file.Path) == budget.WeightLow {
+		return true
+	}
+	if len(file.Hunks) == 0 || file.Hunks[0].NewStart > 1 {
+		return false
+	}
+	for i, line := range file.Hunks[0].Lines {
+		if i == generatedLines {
+			break
+		}
+		if strings.Contains(line, "@generated") ||
+			strings.Contains(line, "Code generated") && strings.Contains(line, "DO NOT EDIT") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package changeset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/budget"
+)
+
+var titles = map[Mode]string{
+	ModeStaged:    "=== STAGED CHANGES ANALYSIS ===",
+	ModeAll:       "=== ALL CHANGES ANALYSIS ===",
+	ModeUntracked: "=== UNTRACKED FILES ANALYSIS ===",
+}
+
+// Render turns the change set into prompt text of about tokens: a summary of
+// the files, then each file's status and hunks. The hunks share what is left
+// of the budget, with generated files getting a smaller share; hunks that do
+// not fit are replaced by a "more lines omitted" line.
+func Render(set *ChangeSet, tokens int) string {
+	var b strings.Builder
+	b.WriteString(titles[set.Mode] + "\n")
+	stats := set.Stats()
+	fmt.Fprintf(&b, "Files changed: %d (+%d -%d)\n", len(set.Files), stats.Added, stats.Deleted)
+
+	paths := make([]string, len(set.Files))
+	for i, file := range set.Files {
+		paths[i] = QuotePath(file.Path)
+	}
+	fmt.Fprintf(&b, "Files: %s\n", strings.Join(paths, " "))
+	if languages := set.languages(); len(languages) > 0 {
+		fmt.Fprintf(&b, "Languages: %s\n", strings.Join(languages, ", "))
+	}
+	b.WriteString("\n=== DETAILED CHANGES ===\n")
+
+	sections := make([]budget.Section, len(set.Files))
+	for i, file := range set.Files {
+		sections[i] = budget.Section{
+			Path:   file.Path,
+			Header: fmt.Sprintf("\n--- %s (%s) ---\n", QuotePath(file.Path), Label(file)),
+			Body:   renderHunks(file.Hunks),
+		}
+		if file.Generated {
+			sections[i].Weight = budget.WeightLow
+		}
+	}
+	bodies := budget.Fit(sections, tokens-budget.EstimateTokens(b.String()))
+	for i, section := range sections {
+		b.WriteString(section.Header)
+		b.WriteString(bodies[i])
+	}
+	return b.String()
+}
+
+// languages returns the languages of the files, most used first.
+func (s *ChangeSet) languages() []string {
+	counts := map[string]int{}
+	var languages []string
+	for _, file := range s.Files {
+		if file.Language == "" {
+			continue
+		}
+		if counts[file.Language] == 0 {
+			languages = append(languages, file.Language)
+		}
+		counts[file.Language]++
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return counts[languages[i]] > counts[languages[j]] })
+	return languages
+}
+
+// Label describes a file's change in a few words, e.g. "renamed from a.go,
+// 92% similar, +3 -1".
+func Label(file File) string {
+	parts := []string{string(file.Status)}
+	switch file.Status {
+	case StatusRenamed, StatusCopied:
+		parts = []string{fmt.Sprintf("%s from %s, %d%% similar", file.Status, QuotePath(file.OldPath), file.Similarity)}
+	case StatusTypeChanged:
+		parts = append(parts, fmt.Sprintf("%s -> %s", fileType(file.OldMode), fileType(file.NewMode)))
+	case StatusAdded, StatusUntracked:
+		if kind := fileType(file.NewMode); kind != "file" {
+			parts = append(parts, kind)
+		}
+	}
+	if file.ModeChanged() && file.Status != StatusTypeChanged {
+		parts = append(parts, fmt.Sprintf("mode %s -> %s", file.OldMode, file.NewMode))
+	}
+	if file.Generated {
+		parts = append(parts, "generated")
+	}
+	switch {
+	case file.Binary:
+		parts = append(parts, "binary")
+	case file.Status == StatusAdded || file.Status == StatusUntracked:
+		parts = append(parts, countLines(file.Stats.Added))
+	case file.Status == StatusDeleted:
+		parts = append(parts, countLines(file.Stats.Deleted)+" removed")
+	case file.Stats.Added+file.Stats.Deleted > 0:
+		parts = append(parts, fmt.Sprintf("+%d -%d", file.Stats.Added, file.Stats.Deleted))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func countLines(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// fileType names the kind of entry a git file mode stands for.
+func fileType(mode string) string {
+	switch mode {
+	case symlinkMode:
+		return "symlink"
+	case "160000":
+		return "submodule"
+	default:
+		return "file"
+	}
+}
+
+// renderHunks writes hunks back as the hunk part of a unified diff.
+func renderHunks(hunks []Hunk) string {
+	var b strings.Builder
+	for _, hunk := range hunks {
+		b.WriteString(hunk.Header + "\n")
+		for _, line := range hunk.Lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,328 @@
+// Package commitlint reads commitlint configuration files and maps their rules
+// onto a commitrules.RuleSet, so generated messages pass the same lint as CI.
+package commitlint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned for configurations commitgen cannot use.
+var (
+	ErrInvalidRule = errors.New("invalid commitlint rule")
+	ErrInvalidFile = errors.New("invalid commitlint config")
+)
+
+// ConventionalPreset is the shareable config whose rules are built in.
+const ConventionalPreset = "@commitlint/config-conventional"
+
+// dataFileNames are the JSON and YAML files commitlint searches, in its order.
+var dataFileNames = []string{
+	"package.json",
+	".commitlintrc",
+	".commitlintrc.json",
+	".commitlintrc.yaml",
+	".commitlintrc.yml",
+}
+
+// scriptFileNames are configs that need a JavaScript runtime to evaluate.
+var scriptFileNames = []string{
+	".commitlintrc.js",
+	".commitlintrc.cjs",
+	".commitlintrc.mjs",
+	".commitlintrc.ts",
+	"commitlint.config.js",
+	"commitlint.config.cjs",
+	"commitlint.config.mjs",
+	"commitlint.config.ts",
+}
+
+// Rule is one commitlint rule: [level, applicable, value].
+type Rule struct {
+	Level commitrules.Level
+	// Never is true when the rule is "never" rather than "always".
+	Never bool
+	Value any
+}
+
+// Config is a parsed commitlint configuration.
+type Config struct {
+	// Path is the file the config was read from.
+	Path    string
+	Extends []string
+	Rules   map[string]Rule
+}
+
+// Find looks for a commitlint config in dir. It returns a nil config when there
+// is none, and warnings for configs that exist but cannot be read, such as
+// commitlint.config.js.
+func Find(dir string) (*Config, []string, error) {
+	var warnings []string
+	for _, name := range dataFileNames {
+		path := filepath.Join(dir, name)
+		//nolint:gosec // G304: path is one of the well-known commitlint file names
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		cfg, err := parseFile(name, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if cfg == nil {
+			// package.json without a "commitlint" key.
+			continue
+		}
+		cfg.Path = path
+		return cfg, warnings, nil
+	}
+
+	for _, name := range scriptFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s is JavaScript and cannot be read; move the rules to .commitlintrc.json or .commitlintrc.yaml to have commitgen follow them", name))
+		}
+	}
+	return nil, warnings, nil
+}
+
+// Parse reads a JSON or YAML commitlint config.
+func Parse(data []byte) (*Config, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		raw = nil
+		if yamlErr := yaml.Unmarshal(data, &raw); yamlErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, yamlErr)
+		}
+	}
+	return fromMap(raw)
+}
+
+func parseFile(name string, data []byte) (*Config, error) {
+	if name != "package.json" {
+		return Parse(data)
+	}
+
+	var pkg struct {
+		Commitlint map[string]any `json:"commitlint"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	if pkg.Commitlint == nil {
+		return nil, nil
+	}
+	return fromMap(pkg.Commitlint)
+}
+
+func fromMap(raw map[string]any) (*Config, error) {
+	cfg := &Config{Rules: map[string]Rule{}}
+
+	switch extends := raw["extends"].(type) {
+	case string:
+		cfg.Extends = []string{extends}
+	case []any:
+		for _, item := range extends {
+			if name, ok := item.(string); ok {
+				cfg.Extends = append(cfg.Extends, name)
+			}
+		}
+	}
+
+	rules, _ := raw["rules"].(map[string]any)
+	for name, value := range rules {
+		rule, err := parseRule(value)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		cfg.Rules[name] = rule
+	}
+	return cfg, nil
+}
+
+func parseRule(value any) (Rule, error) {
+	parts, ok := value.([]any)
+	if !ok || len(parts) == 0 {
+		return Rule{}, fmt.Errorf("%w: expected [level, applicable, value]", ErrInvalidRule)
+	}
+
+	level, ok := toInt(parts[0])
+	if !ok || level < int(commitrules.LevelOff) || level > int(commitrules.LevelError) {
+		return Rule{}, fmt.Errorf("%w: level must be 0, 1 or 2", ErrInvalidRule)
+	}
+	rule := Rule{Level: commitrules.Level(level)}
+
+	if len(parts) > 1 {
+		applicable, _ := parts[1].(string)
+		rule.Never = applicable == "never"
+	}
+	if len(parts) > 2 {
+		rule.Value = parts[2]
+	}
+	return rule, nil
+}
+
+// Apply merges the config's presets and rules into rules. Rules from the file
+// override those of the presets it extends. It returns warnings for presets
+// commitgen does not know and for rules it turns off because it cannot enforce
+// them.
+func (c *Config) Apply(rules *commitrules.RuleSet) []string {
+	var warnings []string
+	merged := map[string]Rule{}
+	for _, preset := range c.Extends {
+		presetRules, ok := presets[preset]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: ignoring unknown preset %q", filepath.Base(c.Path), preset))
+			continue
+		}
+		for name, rule := range presetRules {
+			merged[name] = rule
+		}
+	}
+	for name, rule := range c.Rules {
+		merged[name] = rule
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if warning := applyRule(rules, name, merged[name]); warning != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", filepath.Base(c.Path), warning))
+		}
+	}
+	return warnings
+}
+
+// applyRule maps one commitlint rule onto the rule set. Rules without a
+// commitgen equivalent are ignored; rules commitgen can only enforce the other
+// way round are turned off, with a warning.
+func applyRule(rules *commitrules.RuleSet, name string, rule Rule) string {
+	if rules.Levels == nil {
+		rules.Levels = map[string]commitrules.Level{}
+	}
+	rules.Levels[name] = rule.Level
+	if rule.Level == commitrules.LevelOff {
+		return ""
+	}
+
+	switch name {
+	case commitrules.RuleTypeEnum:
+		applyTypeEnum(rules, rule)
+	case commitrules.RuleScopeEnum:
+		if scopes := toStrings(rule.Value); !rule.Never {
+			rules.Scopes = scopes
+		}
+	case commitrules.RuleScopeEmpty:
+		rules.RequireScope = rule.Never
+	case commitrules.RuleTypeCase:
+		rules.TypeCase = caseRule(rule)
+	case commitrules.RuleScopeCase:
+		rules.ScopeCase = caseRule(rule)
+	case commitrules.RuleSubjectCase:
+		rules.SubjectCase = caseRule(rule)
+	case commitrules.RuleSubjectFullStop:
+		// Generated subjects never end with punctuation, so only "never" is supported.
+		if !rule.Never {
+			rules.Levels[name] = commitrules.LevelOff
+			return fmt.Sprintf("turning off %s: only \"never\" is supported", name)
+		}
+		if stop, ok := rule.Value.(string); ok {
+			rules.SubjectFullStop = stop
+		}
+	case commitrules.RuleHeaderMaxLength:
+		if limit, ok := toInt(rule.Value); ok {
+			rules.HeaderMaxLength = limit
+			if rules.HeaderWarnLength > limit {
+				rules.HeaderWarnLength = limit
+			}
+		}
+	case commitrules.RuleBodyMaxLineLength:
+		if limit, ok := toInt(rule.Value); ok {
+			rules.BodyMaxLineLength = limit
+		}
+	case commitrules.RuleFooterMaxLineLength:
+		if limit, ok := toInt(rule.Value); ok {
+			rules.FooterMaxLineLength = limit
+		}
+	}
+	return ""
+}
+
+func applyTypeEnum(rules *commitrules.RuleSet, rule Rule) {
+	names := toStrings(rule.Value)
+	if rule.Never {
+		types := make(map[string]commitrules.CommitRule, len(rules.Types))
+		for name, commitRule := range rules.Types {
+			types[name] = commitRule
+		}
+		for _, name := range names {
+			delete(types, name)
+		}
+		rules.Types = types
+		return
+	}
+
+	types := make(map[string]commitrules.CommitRule, len(names))
+	for _, name := range names {
+		commitRule, ok := rules.Types[name]
+		if !ok {
+			commitRule = commitrules.CommitRules[name]
+		}
+		commitRule.Type = name
+		if commitRule.Description == "" {
+			commitRule.Description = conventionalTypes[name]
+		}
+		types[name] = commitRule
+	}
+	rules.Types = types
+}
+
+func caseRule(rule Rule) commitrules.CaseRule {
+	return commitrules.CaseRule{Cases: toStrings(rule.Value), Never: rule.Never}
+}
+
+// toStrings accepts a single string or a list of strings.
+func toStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				items = append(items, s)
+			}
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// toInt accepts the number types produced by the JSON and YAML decoders.
+func toInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), v == float64(int(v))
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,154 @@
+package commitlint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestFindJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".commitlintrc.json", `{
+	"extends": ["@commitlint/config-conventional"],
+	"rules": {
+		"scope-enum": [2, "always", ["api", "ui"]],
+		"header-max-length": [2, "always", 60],
+		"body-max-line-length": [1, "always", 80]
+	}
+}`)
+
+	cfg, warnings, err := Find(dir)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if cfg == nil || len(warnings) != 0 {
+		t.Fatalf("Find = %+v, %v; want a config and no warnings", cfg, warnings)
+	}
+
+	rules := commitrules.NewRuleSet()
+	if warnings := cfg.Apply(rules); len(warnings) != 0 {
+		t.Errorf("Apply warnings = %v", warnings)
+	}
+	if rules.HeaderMaxLength != 60 || rules.BodyMaxLineLength != 80 {
+		t.Errorf("limits = %d/%d, want 60/80", rules.HeaderMaxLength, rules.BodyMaxLineLength)
+	}
+	if got := strings.Join(rules.Scopes, ","); got != "api,ui" {
+		t.Errorf("Scopes = %v", rules.Scopes)
+	}
+	if _, ok := rules.Types["perf"]; !ok {
+		t.Error("config-conventional types should include perf")
+	}
+	if rules.Types["ci"].Description == "" {
+		t.Error("preset types should keep a description for the prompt")
+	}
+	if rules.Levels[commitrules.RuleBodyMaxLineLength] != commitrules.LevelWarning {
+		t.Errorf("body-max-line-length level = %v, want warning", rules.Levels[commitrules.RuleBodyMaxLineLength])
+	}
+}
+
+func TestFindYAMLAndPackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "package.json", `{"name": "app"}`)
+	writeFile(t, dir, ".commitlintrc.yaml", "rules:\n  type-enum: [2, always, [feat, fix, deps]]\n  subject-full-stop: [2, never, '.']\n")
+
+	cfg, _, err := Find(dir)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if filepath.Base(cfg.Path) != ".commitlintrc.yaml" {
+		t.Errorf("Path = %s, want the YAML file since package.json has no commitlint key", cfg.Path)
+	}
+
+	writeFile(t, dir, "package.json", `{"commitlint": {"rules": {"header-max-length": [2, "always", 50]}}}`)
+	cfg, _, err = Find(dir)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if filepath.Base(cfg.Path) != "package.json" {
+		t.Errorf("Path = %s, want package.json first", cfg.Path)
+	}
+}
+
+func TestApplyEnforcesRules(t *testing.T) {
+	cfg, err := Parse([]byte(`
+extends: "@commitlint/config-conventional"
+rules:
+  type-enum: [2, always, [feat, fix, deps]]
+  scope-empty: [2, never]
+  body-leading-blank: [0]
+`))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	rules := commitrules.NewRuleSet()
+	cfg.Apply(rules)
+
+	tests := []struct {
+		message string
+		want    error
+	}{
+		{"deps(go): bump yaml", nil},
+		{"perf(db): batch inserts", commitrules.ErrInvalidType},
+		{"fix: handle nil rows", commitrules.ErrInvalidScope},
+		{"fix(api): Handle nil rows", commitrules.ErrInvalidFormat},
+		{"fix(api): handle nil rows.", commitrules.ErrInvalidFormat},
+		{"fix(api): handle nil rows\nno blank line", nil},
+	}
+	for _, test := range tests {
+		err := rules.Validate(test.message)
+		if !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+			t.Errorf("Validate(%q) = %v, want %v", test.message, err, test.want)
+		}
+	}
+}
+
+func TestApplyTurnsOffRequiredFullStop(t *testing.T) {
+	cfg, err := Parse([]byte(`{"rules": {"subject-full-stop": [2, "always", "."]}}`))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	rules := commitrules.NewRuleSet()
+	warnings := cfg.Apply(rules)
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "subject-full-stop") {
+		t.Errorf("warnings = %q, want one about subject-full-stop", warnings)
+	}
+	if rules.Levels[commitrules.RuleSubjectFullStop] != commitrules.LevelOff {
+		t.Errorf("subject-full-stop level = %v, want off", rules.Levels[commitrules.RuleSubjectFullStop])
+	}
+	for _, message := range []string{"fix(api): handle nil rows.", "fix(api): handle nil rows"} {
+		if findings := rules.Lint(message); len(findings) != 0 {
+			t.Errorf("Lint(%q) = %v, want no findings", message, findings)
+		}
+	}
+}
+
+func TestFindWarnsAboutJavaScript(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "commitlint.config.js", "module.exports = {}")
+
+	cfg, warnings, err := Find(dir)
+	if err != nil || cfg != nil {
+		t.Fatalf("Find = %+v, %v; want no config", cfg, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "commitlint.config.js") {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestParseRejectsBadLevel(t *testing.T) {
+	if _, err := Parse([]byte(`{"rules": {"header-max-length": [3, "always", 50]}}`)); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("Parse error = %v, want ErrInvalidRule", err)
+	}
+}
@@ -0,0 +1,36 @@
+package commitlint
+
+import "github.com/FreePeak/commitgen/pkg/commitrules"
+
+// presets holds the rules of the shareable configs commitgen knows without npm.
+var presets = map[string]map[string]Rule{
+	ConventionalPreset: {
+		"body-leading-blank":     {Level: commitrules.LevelWarning},
+		"body-max-line-length":   {Level: commitrules.LevelError, Value: 100},
+		"footer-leading-blank":   {Level: commitrules.LevelWarning},
+		"footer-max-line-length": {Level: commitrules.LevelError, Value: 100},
+		"header-max-length":      {Level: commitrules.LevelError, Value: 100},
+		"subject-case": {
+			Level: commitrules.LevelError,
+			Never: true,
+			Value: []any{"sentence-case", "start-case", "pascal-case", "upper-case"},
+		},
+		"subject-empty":     {Level: commitrules.LevelError, Never: true},
+		"subject-full-stop": {Level: commitrules.LevelError, Never: true, Value: "."},
+		"type-case":         {Level: commitrules.LevelError, Value: "lower-case"},
+		"type-empty":        {Level: commitrules.LevelError, Never: true},
+		"type-enum": {
+			Level: commitrules.LevelError,
+			Value: []any{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+		},
+	},
+}
+
+// conventionalTypes describes the config-conventional types that are not built
+// into commitrules, so prompts can still explain them.
+var conventionalTypes = map[string]string{
+	"build":  "Changes that affect the build system or external dependencies",
+	"ci":     "Changes to CI configuration files and scripts",
+	"perf":   "A code change that improves performance",
+	"revert": "Reverts a previous commit",
+}
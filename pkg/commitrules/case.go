@@ -0,0 +1,75 @@
+package commitrules
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CaseRule requires (or, with Never, forbids) text to be in one of the named
+// cases. The names follow commitlint: lower-case, upper-case, sentence-case,
+// start-case, pascal-case, camel-case, kebab-case and snake-case.
+type CaseRule struct {
+	Cases []string
+	Never bool
+}
+
+// Allows reports whether text satisfies the rule. An empty rule allows everything.
+func (c CaseRule) Allows(text string) bool {
+	if len(c.Cases) == 0 || text == "" {
+		return true
+	}
+	matched := false
+	for _, name := range c.Cases {
+		if IsCase(text, name) {
+			matched = true
+			break
+		}
+	}
+	return matched != c.Never
+}
+
+// IsCase reports whether text is written in the named case. Unknown names never match.
+func IsCase(text, name string) bool {
+	words := strings.FieldsFunc(text, func(r rune) bool { return unicode.IsSpace(r) })
+	switch name {
+	case "lower-case", "lowercase":
+		return text == strings.ToLower(text)
+	case "upper-case", "uppercase":
+		return strings.IndexFunc(text, unicode.IsLetter) >= 0 && text == strings.ToUpper(text)
+	case "sentence-case", "sentencecase":
+		return startsUpper(text)
+	case "start-case":
+		for _, word := range words {
+			if !startsUpper(word) {
+				return false
+			}
+		}
+		return len(words) > 0
+	case "pascal-case":
+		return len(words) == 1 && startsUpper(text) && !strings.ContainsAny(text, "-_")
+	case "camel-case":
+		return len(words) == 1 && text == lowerFirst(text) && !strings.ContainsAny(text, "-_")
+	case "kebab-case":
+		return len(words) == 1 && text == strings.ToLower(text) && !strings.Contains(text, "_")
+	case "snake-case":
+		return len(words) == 1 && text == strings.ToLower(text) && !strings.Contains(text, "-")
+	default:
+		return false
+	}
+}
+
+// startsUpper reports whether the first rune is an upper-case letter, so "2fa
+// login" is not mistaken for sentence case.
+func startsUpper(text string) bool {
+	for _, r := range text {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+func lowerFirst(text string) string {
+	for i, r := range text {
+		return text[:i] + string(unicode.ToLower(r)) + text[i+len(string(r)):]
+	}
+	return text
+}
@@ -0,0 +1,62 @@
+package commitrules
+
+import "strings"
+
+// imperativeVerbs are common first words of commit subjects. Inflected forms of
+// these ("added", "fixes", "updating") are reported by the subject-imperative rule.
+var imperativeVerbs = map[string]bool{
+	"add": true, "allow": true, "avoid": true, "bump": true, "change": true, "clean": true,
+	"configure": true, "convert": true, "correct": true, "create": true, "delete": true,
+	"deprecate": true, "disable": true, "document": true, "drop": true, "enable": true,
+	"ensure": true, "expose": true, "extract": true, "fix": true, "format": true,
+	"handle": true, "implement": true, "improve": true, "initialize": true, "install": true,
+	"introduce": true, "load": true, "make": true, "merge": true, "migrate": true,
+	"move": true, "optimize": true, "parse": true, "prevent": true, "reduce": true,
+	"refactor": true, "remove": true, "rename": true, "replace": true, "resolve": true,
+	"restore": true, "revert": true, "rewrite": true, "set": true, "simplify": true,
+	"split": true, "stop": true, "support": true, "test": true, "update": true,
+	"upgrade": true, "use": true, "validate": true,
+}
+
+// nonImperative returns the first word of subject when it is an inflected form
+// of a known verb, such as "adds" or "fixed".
+func nonImperative(subject string) (string, bool) {
+	fields := strings.Fields(subject)
+	if len(fields) == 0 {
+		return "", false
+	}
+	word := strings.ToLower(fields[0])
+	if imperativeVerbs[word] {
+		return "", false
+	}
+	if imperativeOf(word) != "" {
+		return fields[0], true
+	}
+	return "", false
+}
+
+// imperativeOf returns the known verb that word is an inflection of, or "".
+func imperativeOf(word string) string {
+	word = strings.ToLower(word)
+	for _, suffix := range []string{"ing", "ed", "es", "s", "d"} {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if !ok || stem == "" {
+			continue
+		}
+		candidates := []string{stem, stem + "e"}
+		if strings.HasSuffix(stem, "i") {
+			// simplified, simplifies
+			candidates = append(candidates, strings.TrimSuffix(stem, "i")+"y")
+		}
+		if n := len(stem); n > 1 && stem[n-1] == stem[n-2] {
+			// dropped, setting
+			candidates = append(candidates, stem[:n-1])
+		}
+		for _, candidate := range candidates {
+			if imperativeVerbs[candidate] {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,343 @@
+package commitrules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule IDs. Where commitlint has an equivalent rule the ID is the same.
+const (
+	RuleHeaderFormat            = "header-format"
+	RuleTypeEmpty               = "type-empty"
+	RuleTypeEnum                = "type-enum"
+	RuleTypeCase                = "type-case"
+	RuleScopeEnum               = "scope-enum"
+	RuleScopeCase               = "scope-case"
+	RuleScopeEmpty              = "scope-empty"
+	RuleSubjectEmpty            = "subject-empty"
+	RuleSubjectCase             = "subject-case"
+	RuleSubjectFullStop         = "subject-full-stop"
+	RuleSubjectImperative       = "subject-imperative"
+	RuleHeaderMaxLength         = "header-max-length"
+	RuleHeaderRecommendedLength = "header-recommended-length"
+	RuleBodyLeadingBlank        = "body-leading-blank"
+	RuleBodyMaxLineLength       = "body-max-line-length"
+	RuleFooterFormat            = "footer-format"
+	RuleFooterMaxLineLength     = "footer-max-line-length"
+)
+
+// Level is the severity of a rule, using commitlint's numbering.
+type Level int
+
+// Rule levels.
+const (
+	LevelOff     Level = 0
+	LevelWarning Level = 1
+	LevelError   Level = 2
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelOff:
+		return "off"
+	case LevelWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// MarshalText encodes the level as its name, e.g. "warning".
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// defaultLevels lists the rules that are only warnings unless configured
+// otherwise; every other rule is an error.
+var defaultLevels = map[string]Level{
+	RuleSubjectCase:             LevelWarning,
+	RuleSubjectFullStop:         LevelWarning,
+	RuleSubjectImperative:       LevelWarning,
+	RuleHeaderRecommendedLength: LevelWarning,
+}
+
+// DefaultLevel returns the severity a rule has when RuleSet.Levels does not list it.
+func DefaultLevel(ruleID string) Level {
+	if level, ok := defaultLevels[ruleID]; ok {
+		return level
+	}
+	return LevelError
+}
+
+// Position is a 1-based line and column in the commit message.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Finding is one rule violation. It implements error and unwraps to the
+// matching sentinel error, e.g. ErrInvalidType.
+type Finding struct {
+	RuleID   string   `json:"rule"`
+	Severity Level    `json:"severity"`
+	Message  string   `json:"message"`
+	Position Position `json:"position"`
+
+	kind error
+}
+
+func (f Finding) Error() string {
+	return f.Message
+}
+
+// Unwrap returns the sentinel error for the finding's category.
+func (f Finding) Unwrap() error {
+	return f.kind
+}
+
+// String formats the finding as "line:column severity message [rule]".
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d %s %s [%s]", f.Position.Line, f.Position.Column, f.Severity, f.Message, f.RuleID)
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks message against the default rule set.
+func Lint(message string) []Finding {
+	return Default.Lint(message)
+}
+
+// ignoredHeaders match the headers git and hosting services write themselves,
+// following commitlint's default ignores.
+var ignoredHeaders = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge (pull request|branch|tag|remote-tracking branch) `),
+	regexp.MustCompile(`^Merge .+ into `),
+	regexp.MustCompile(`^Merged .+ (in|into) `),
+	regexp.MustCompile(`^Auto-merged .+ into `),
+	regexp.MustCompile(`^Automatic merge`),
+	regexp.MustCompile(`^[Rr]evert `),
+	regexp.MustCompile(`^(amend|fixup|squash)! `),
+}
+
+// Ignored reports whether message is a merge, revert, fixup! or squash!
+// commit, which is not linted.
+func Ignored(message string) bool {
+	header, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	for _, pattern := range ignoredHeaders {
+		if pattern.MatchString(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerRegexp splits "type(scope)!: subject".
+var headerRegexp = regexp.MustCompile(`^([^():\s!]*)(?:\(([^)]*)\))?(!)?:(.*)$`)
+
+// parsedMessage is a commit message with the positions the rules report.
+type parsedMessage struct {
+	lines []string
+
+	commitType string
+	scope      string
+	hasScope   bool
+	subject    string
+	scopeCol   int
+	subjectCol int
+
+	// bodyLines and footerLines are 0-based line indexes.
+	bodyLines   []int
+	footerLines []int
+	footers     []Footer
+}
+
+// Lint runs every rule over message and returns the findings in message order
+// of the rules. Rules at LevelOff are skipped.
+func (r *RuleSet) Lint(message string) []Finding {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	lines := strings.Split(message, "\n")
+	header := lines[0]
+	l := linter{rules: r}
+
+	match := headerRegexp.FindStringSubmatchIndex(header)
+	if match == nil {
+		l.report(RuleHeaderFormat, ErrInvalidFormat, Position{1, 1},
+			"commit message must follow format: type(scope): description")
+		return l.findings
+	}
+
+	rawSubject := header[match[8]:match[9]]
+	msg := &parsedMessage{
+		lines:      lines,
+		commitType: header[match[2]:match[3]],
+		hasScope:   match[4] >= 0,
+		subject:    strings.TrimSpace(rawSubject),
+		scopeCol:   match[3] + 2,
+		subjectCol: match[8] + len(rawSubject) - len(strings.TrimLeft(rawSubject, " ")) + 1,
+	}
+	if msg.hasScope {
+		msg.scope = header[match[4]:match[5]]
+	}
+	msg.splitBody()
+
+	l.checkHeader(msg)
+	l.checkBody(msg)
+	return l.findings
+}
+
+// splitBody assigns the lines after the header to the body or the footers.
+func (m *parsedMessage) splitBody() {
+	if len(m.lines) < 2 {
+		return
+	}
+	last := len(m.lines)
+	start := last
+	for start > 1 && strings.TrimSpace(m.lines[start-1]) != "" {
+		start--
+	}
+	if start > 1 {
+		if footers, ok := parseFooters(strings.Join(m.lines[start:last], "\n")); ok {
+			m.footers = footers
+			for i := start; i < last; i++ {
+				m.footerLines = append(m.footerLines, i)
+			}
+			last = start
+		}
+	}
+	for i := 1; i < last; i++ {
+		m.bodyLines = append(m.bodyLines, i)
+	}
+}
+
+type linter struct {
+	rules    *RuleSet
+	findings []Finding
+}
+
+func (l *linter) level(ruleID string) Level {
+	if level, ok := l.rules.Levels[ruleID]; ok {
+		return level
+	}
+	return DefaultLevel(ruleID)
+}
+
+func (l *linter) report(ruleID string, kind error, pos Position, format string, args ...any) {
+	level := l.level(ruleID)
+	if level == LevelOff {
+		return
+	}
+	l.findings = append(l.findings, Finding{
+		RuleID:   ruleID,
+		Severity: level,
+		Message:  fmt.Sprintf(format, args...),
+		Position: pos,
+		kind:     kind,
+	})
+}
+
+func (l *linter) checkHeader(m *parsedMessage) {
+	r := l.rules
+	header := m.lines[0]
+
+	if m.commitType == "" {
+		l.report(RuleTypeEmpty, ErrMissingType, Position{1, 1}, "commit message must have a type")
+	} else {
+		if _, exists := r.Types[m.commitType]; !exists {
+			l.report(RuleTypeEnum, ErrInvalidType, Position{1, 1},
+				"invalid commit type: %s. Valid types: %s", m.commitType, strings.Join(r.CommitTypes(), ", "))
+		}
+		if !r.TypeCase.Allows(m.commitType) {
+			l.report(RuleTypeCase, ErrInvalidType, Position{1, 1},
+				"commit type %q must %s", m.commitType, describeCase(r.TypeCase))
+		}
+	}
+
+	if m.hasScope {
+		if !r.allowsScope(m.scope) {
+			l.report(RuleScopeEnum, ErrInvalidScope, Position{1, m.scopeCol},
+				"invalid commit scope: %s. Valid scopes: %s", m.scope, strings.Join(r.Scopes, ", "))
+		}
+		if !r.ScopeCase.Allows(m.scope) {
+			l.report(RuleScopeCase, ErrInvalidScope, Position{1, m.scopeCol},
+				"commit scope %q must %s", m.scope, describeCase(r.ScopeCase))
+		}
+	} else if r.RequireScope {
+		l.report(RuleScopeEmpty, ErrInvalidScope, Position{1, len(m.commitType) + 1}, "commit message must have a scope")
+	}
+
+	subjectPos := Position{1, m.subjectCol}
+	if m.subject == "" {
+		l.report(RuleSubjectEmpty, ErrInvalidFormat, subjectPos, "commit message must have a description after the colon")
+	} else {
+		if !r.SubjectCase.Allows(m.subject) {
+			l.report(RuleSubjectCase, ErrInvalidFormat, subjectPos, "subject must %s", describeCase(r.SubjectCase))
+		}
+		if r.SubjectFullStop != "" && strings.HasSuffix(m.subject, r.SubjectFullStop) {
+			l.report(RuleSubjectFullStop, ErrInvalidFormat, Position{1, len(header) - len(r.SubjectFullStop) + 1},
+				"subject must not end with %q", r.SubjectFullStop)
+		}
+		if word, ok := nonImperative(m.subject); ok {
+			l.report(RuleSubjectImperative, ErrInvalidFormat, subjectPos,
+				"subject should use the imperative mood (%q, not %q)", imperativeOf(word), word)
+		}
+	}
+
+	if len(header) > r.HeaderMaxLength {
+		l.report(RuleHeaderMaxLength, ErrTooLong, Position{1, r.HeaderMaxLength + 1},
+			"commit message is too long: %d characters (maximum: %d)", len(header), r.HeaderMaxLength)
+	} else if len(header) > r.HeaderWarnLength {
+		l.report(RuleHeaderRecommendedLength, ErrTooLong, Position{1, r.HeaderWarnLength + 1},
+			"commit message is %d characters (recommended: <%d)", len(header), r.HeaderWarnLength)
+	}
+}
+
+func (l *linter) checkBody(m *parsedMessage) {
+	r := l.rules
+	if len(m.lines) > 1 && strings.TrimSpace(m.lines[1]) != "" {
+		l.report(RuleBodyLeadingBlank, ErrMissingBlankLine, Position{2, 1},
+			"commit message body must be separated from the header by a blank line")
+	}
+
+	for _, i := range m.bodyLines {
+		line := m.lines[i]
+		// Long URLs cannot be wrapped.
+		if len(line) > r.BodyMaxLineLength && !strings.Contains(line, "://") {
+			l.report(RuleBodyMaxLineLength, ErrBodyLineTooLong, Position{i + 1, r.BodyMaxLineLength + 1},
+				"body line is %d characters (maximum: %d)", len(line), r.BodyMaxLineLength)
+		}
+	}
+
+	footer := -1
+	for _, i := range m.footerLines {
+		line := m.lines[i]
+		if footerPattern.MatchString(line) {
+			footer++
+		}
+		if r.FooterMaxLineLength > 0 && len(line) > r.FooterMaxLineLength {
+			l.report(RuleFooterMaxLineLength, ErrInvalidFooter, Position{i + 1, r.FooterMaxLineLength + 1},
+				"footer line is %d characters (maximum: %d)", len(line), r.FooterMaxLineLength)
+		}
+		if footer >= 0 && footerPattern.MatchString(line) && m.footers[footer].Token == BreakingChangeToken &&
+			strings.TrimSpace(m.footers[footer].Value) == "" {
+			l.report(RuleFooterFormat, ErrInvalidFooter, Position{i + 1, 1}, "%s footer needs a description", BreakingChangeToken)
+		}
+	}
+}
+
+// describeCase turns a case rule into "be lower-case" or "not be sentence-case or upper-case".
+func describeCase(rule CaseRule) string {
+	cases := strings.Join(rule.Cases, " or ")
+	if rule.Never {
+		return "not be " + cases
+	}
+	return "be " + cases
+}
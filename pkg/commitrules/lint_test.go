@@ -0,0 +1,105 @@
+package commitrules
+
+import (
+	"errors"
+	"testing"
+)
+
+func findRule(findings []Finding, ruleID string) (Finding, bool) {
+	for _, finding := range findings {
+		if finding.RuleID == ruleID {
+			return finding, true
+		}
+	}
+	return Finding{}, false
+}
+
+func TestLintReportsRulesWithPositions(t *testing.T) {
+	message := "feat(api): Added widgets.\nno blank line"
+	findings := NewRuleSet().Lint(message)
+
+	tests := []struct {
+		rule     string
+		severity Level
+		position Position
+	}{
+		{RuleSubjectCase, LevelWarning, Position{1, 12}},
+		{RuleSubjectFullStop, LevelWarning, Position{1, 25}},
+		{RuleSubjectImperative, LevelWarning, Position{1, 12}},
+		{RuleBodyLeadingBlank, LevelError, Position{2, 1}},
+	}
+	for _, tt := range tests {
+		finding, ok := findRule(findings, tt.rule)
+		if !ok {
+			t.Errorf("missing %s finding in %v", tt.rule, findings)
+			continue
+		}
+		if finding.Severity != tt.severity || finding.Position != tt.position {
+			t.Errorf("%s = %s at %+v, want %s at %+v", tt.rule, finding.Severity, finding.Position, tt.severity, tt.position)
+		}
+	}
+	if !HasErrors(findings) {
+		t.Error("HasErrors = false, want true for a missing blank line")
+	}
+}
+
+func TestLintCleanMessage(t *testing.T) {
+	message := "fix(api): handle nil rows\n\nRows can be nil when the query is cancelled.\n\nRefs: #12"
+	if findings := NewRuleSet().Lint(message); len(findings) != 0 {
+		t.Errorf("Lint = %v, want no findings", findings)
+	}
+}
+
+func TestLintFindingsUnwrapToSentinels(t *testing.T) {
+	findings := NewRuleSet().Lint("feature: add widgets")
+	finding, ok := findRule(findings, RuleTypeEnum)
+	if !ok || !errors.Is(finding, ErrInvalidType) {
+		t.Errorf("Lint = %v, want a type-enum finding wrapping ErrInvalidType", findings)
+	}
+
+	if finding, ok := findRule(NewRuleSet().Lint("add widgets"), RuleHeaderFormat); !ok || !errors.Is(finding, ErrInvalidFormat) {
+		t.Errorf("want a header-format finding wrapping ErrInvalidFormat, got %v", finding)
+	}
+}
+
+func TestLintLevels(t *testing.T) {
+	rules := NewRuleSet()
+	rules.Levels = map[string]Level{
+		RuleSubjectImperative: LevelError,
+		RuleBodyLeadingBlank:  LevelOff,
+	}
+	findings := rules.Lint("fix: fixes crash\nno blank line")
+
+	if _, ok := findRule(findings, RuleBodyLeadingBlank); ok {
+		t.Errorf("Lint = %v, want body-leading-blank turned off", findings)
+	}
+	if err := rules.Validate("fix: fixes crash"); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Validate = %v, want subject-imperative raised to an error", err)
+	}
+	if err := NewRuleSet().Validate("fix: fixes crash"); err != nil {
+		t.Errorf("Validate = %v, want warnings to pass", err)
+	}
+}
+
+func TestNonImperative(t *testing.T) {
+	tests := map[string]string{
+		"adds widgets":       "add",
+		"Fixed the crash":    "fix",
+		"simplifies parsing": "simplify",
+		"dropped support":    "drop",
+		"updating docs":      "update",
+		"add widgets":        "",
+		"address review":     "",
+		"setup CI":           "",
+	}
+	for subject, want := range tests {
+		word, ok := nonImperative(subject)
+		got := ""
+		if ok {
+			got = imperativeOf(word)
+		}
+		if got != want {
+			t.Errorf("nonImperative(%q) suggests %q, want %q", subject, got, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package commitrules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Footer is a git trailer such as "BREAKING CHANGE: drop v1 API" or "Refs: #123".
+// Separator is ": " or " #" as written; an empty separator renders as ": ".
+type Footer struct {
+	Token     string
+	Separator string
+	Value     string
+}
+
+func (f Footer) String() string {
+	if f.Separator == "" {
+		return f.Token + ": " + f.Value
+	}
+	return f.Token + f.Separator + f.Value
+}
+
+// BreakingChangeToken is the footer token that marks a breaking change.
+const BreakingChangeToken = "BREAKING CHANGE"
+
+// Message is a conventional commit split into its three parts.
+type Message struct {
+	Header  string
+	Body    string
+	Footers []Footer
+}
+
+var (
+	footerPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z0-9-]*)(: | #)(.*)$`)
+	headerPattern = regexp.MustCompile(`^[a-z]+(\([^)]*\))?!?: \S`)
+)
+
+// ParseCommitMessage splits a message into header, body and footers. The footers
+// are the last paragraph when every line of it starts with a trailer token.
+func ParseCommitMessage(text string) Message {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	header, rest, _ := strings.Cut(text, "\n")
+	msg := Message{Header: strings.TrimSpace(header)}
+
+	paragraphs := splitParagraphs(rest)
+	if n := len(paragraphs); n > 0 {
+		if footers, ok := parseFooters(paragraphs[n-1]); ok {
+			msg.Footers = footers
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	msg.Body = strings.Join(paragraphs, "\n\n")
+	return msg
+}
+
+// String renders the message with a blank line between header, body and footers.
+func (m Message) String() string {
+	parts := []string{m.Header}
+	if m.Body != "" {
+		parts = append(parts, m.Body)
+	}
+	if len(m.Footers) > 0 {
+		lines := make([]string, 0, len(m.Footers))
+		for _, footer := range m.Footers {
+			lines = append(lines, footer.String())
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// IsBreaking reports whether the header has a "!" marker or a BREAKING CHANGE footer is present.
+func (m Message) IsBreaking() bool {
+	if typeAndScope, _, ok := strings.Cut(m.Header, ":"); ok && strings.HasSuffix(typeAndScope, "!") {
+		return true
+	}
+	for _, footer := range m.Footers {
+		if footer.Token == BreakingChangeToken || footer.Token == "BREAKING-CHANGE" {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanCommitMessageWithBody cleans a generated message while keeping its body and
+// footers. Any preamble before the header is dropped and the body is wrapped.
+func CleanCommitMessageWithBody(message string) string {
+	return Default.CleanCommitMessageWithBody(message)
+}
+
+// CleanCommitMessageWithBody is like the package-level function but wraps the body
+// at the rule set's line length.
+func (r *RuleSet) CleanCommitMessageWithBody(message string) string {
+	message = strings.TrimSpace(stripCodeFence(message))
+	message = strings.TrimSpace(strings.Trim(message, `"'`))
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+
+	start := 0
+	for i, line := range lines {
+		if headerPattern.MatchString(strings.TrimSpace(line)) {
+			start = i
+			break
+		}
+	}
+
+	header := CleanCommitMessage(lines[start])
+	msg := ParseCommitMessage(header + "\n" + strings.Join(lines[start+1:], "\n"))
+	msg.Body = WrapText(msg.Body, r.BodyMaxLineLength)
+	return msg.String()
+}
+
+// WrapText wraps each paragraph of text at width columns. Lines starting with
+// "- " or "* " are kept as separate list items with a hanging indent.
+func WrapText(text string, width int) string {
+	paragraphs := splitParagraphs(text)
+	wrapped := make([]string, 0, len(paragraphs))
+	for _, paragraph := range paragraphs {
+		var items []string
+		for _, line := range strings.Split(paragraph, "\n") {
+			line = strings.TrimSpace(line)
+			if isListItem(line) || len(items) == 0 {
+				items = append(items, line)
+				continue
+			}
+			items[len(items)-1] += " " + line
+		}
+
+		lines := make([]string, 0, len(items))
+		for _, item := range items {
+			indent := ""
+			if isListItem(item) {
+				indent = "  "
+			}
+			lines = append(lines, wrapLine(item, width, indent)...)
+		}
+		wrapped = append(wrapped, strings.Join(lines, "\n"))
+	}
+	return strings.Join(wrapped, "\n\n")
+}
+
+func wrapLine(text string, width int, indent string) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if len(current)+1+len(word) > width {
+			lines = append(lines, current)
+			current = indent + word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current)
+}
+
+func isListItem(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+func parseFooters(paragraph string) ([]Footer, bool) {
+	var footers []Footer
+	for _, line := range strings.Split(paragraph, "\n") {
+		match := footerPattern.FindStringSubmatch(line)
+		switch {
+		case match != nil:
+			footers = append(footers, Footer{Token: match[1], Separator: match[2], Value: match[3]})
+		case len(footers) > 0 && strings.HasPrefix(line, " "):
+			// Indented lines continue the previous footer value.
+			footers[len(footers)-1].Value += "\n" + line
+		default:
+			return nil, false
+		}
+	}
+	return footers, len(footers) > 0
+}
+
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t"))
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, "\n"))
+	}
+	return paragraphs
+}
+
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	_, text, _ = strings.Cut(text, "\n")
+	return strings.TrimSuffix(strings.TrimSpace(text), "```")
+}
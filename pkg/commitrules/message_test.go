@@ -0,0 +1,170 @@
+package commitrules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCommitMessage(t *testing.T) {
+	text := "feat(api)!: drop v1 endpoints\n\nThe v1 handlers duplicated v2 and nobody\nhas called them since March.\n\nBREAKING CHANGE: /v1 routes return 404\nRefs: #42"
+
+	msg := ParseCommitMessage(text)
+	if msg.Header != "feat(api)!: drop v1 endpoints" {
+		t.Errorf("Header = %q", msg.Header)
+	}
+	if msg.Body != "The v1 handlers duplicated v2 and nobody\nhas called them since March." {
+		t.Errorf("Body = %q", msg.Body)
+	}
+	want := []Footer{
+		{Token: BreakingChangeToken, Separator: ": ", Value: "/v1 routes return 404"},
+		{Token: "Refs", Separator: ": ", Value: "#42"},
+	}
+	if len(msg.Footers) != len(want) {
+		t.Fatalf("Footers = %+v, want %+v", msg.Footers, want)
+	}
+	for i := range want {
+		if msg.Footers[i] != want[i] {
+			t.Errorf("Footers[%d] = %+v, want %+v", i, msg.Footers[i], want[i])
+		}
+	}
+	if !msg.IsBreaking() {
+		t.Error("IsBreaking() = false, want true")
+	}
+	if got := msg.String(); got != text {
+		t.Errorf("String() = %q, want %q", got, text)
+	}
+}
+
+func TestParseCommitMessageWithoutFooters(t *testing.T) {
+	msg := ParseCommitMessage("fix: handle nil config\n\nSee: the last paragraph is prose, not trailers.\nIt only looks like one.")
+	if len(msg.Footers) != 0 {
+		t.Errorf("Footers = %+v, want none", msg.Footers)
+	}
+	if !strings.HasPrefix(msg.Body, "See:") {
+		t.Errorf("Body = %q", msg.Body)
+	}
+}
+
+func TestCleanCommitMessageWithBody(t *testing.T) {
+	input := "```\nHere is the commit message:\nfix(cache): evict entries on config reload\n\n" +
+		"Reloading the config changed the key format but kept the old entries around, so lookups silently missed and every request went to the backend.\n\n" +
+		"Refs: #17\n```"
+
+	got := CleanCommitMessageWithBody(input)
+	want := "fix(cache): evict entries on config reload\n\n" +
+		"Reloading the config changed the key format but kept the old entries\n" +
+		"around, so lookups silently missed and every request went to the\n" +
+		"backend.\n\n" +
+		"Refs: #17"
+	if got != want {
+		t.Errorf("CleanCommitMessageWithBody() =\n%s\nwant\n%s", got, want)
+	}
+	if err := ValidateCommitMessage(got); err != nil {
+		t.Errorf("cleaned message failed validation: %v", err)
+	}
+}
+
+func TestWrapTextKeepsListItems(t *testing.T) {
+	got := WrapText("- first item that is long enough to need wrapping at this narrow width\n- second", 40)
+	want := "- first item that is long enough to need\n  wrapping at this narrow width\n- second"
+	if got != want {
+		t.Errorf("WrapText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidateCommitMessageBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    error
+	}{
+		{"subject only", "feat: add retries", nil},
+		{"with body", "feat: add retries\n\nBackends drop requests under load.", nil},
+		{"missing blank line", "feat: add retries\nBackends drop requests under load.", ErrMissingBlankLine},
+		{"long body line", "feat: add retries\n\n" + strings.Repeat("word ", 20), ErrBodyLineTooLong},
+		{"long url is allowed", "docs: link spec\n\nhttps://example.com/" + strings.Repeat("a", 80), nil},
+		{"breaking footer", "feat!: drop v1\n\nBREAKING CHANGE: v1 is gone", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateCommitMessage(test.message)
+			if !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+				t.Errorf("ValidateCommitMessage() = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildPromptBodyMode(t *testing.T) {
+	prompt := BuildPrompt("diff --git a/x b/x", PromptOptions{Body: true, Guidance: "mention the cache"})
+	for _, element := range []string{"BREAKING CHANGE:", "Refs:", "explains WHY", "mention the cache", "diff --git a/x b/x"} {
+		if !strings.Contains(prompt, element) {
+			t.Errorf("body prompt missing %q", element)
+		}
+	}
+}
+
+func TestRuleSetLimits(t *testing.T) {
+	rules := NewRuleSet()
+	rules.HeaderMaxLength = 20
+	if err := rules.Validate("feat: add a rather long subject"); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Validate() = %v, want ErrTooLong with a 20 character limit", err)
+	}
+	if err := ValidateCommitMessage("feat: add a rather long subject"); err != nil {
+		t.Errorf("the default rule set should be unaffected, got %v", err)
+	}
+}
+
+func TestRuleSetTypesAndScopes(t *testing.T) {
+	rules := NewRuleSet()
+	rules.Types = map[string]CommitRule{
+		"perf": {Type: "perf", Description: "Performance improvements", Examples: []string{"perf(db): batch inserts"}},
+		"fix":  CommitRules["fix"],
+	}
+	rules.Scopes = []string{"db", "api"}
+
+	if err := rules.Validate("perf(db): batch inserts"); err != nil {
+		t.Errorf("Validate(perf) = %v, want nil", err)
+	}
+	if err := rules.Validate("fix: handle nil rows"); err != nil {
+		t.Errorf("a message without scope should pass, got %v", err)
+	}
+	if err := rules.Validate("feat(db): add index"); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("Validate(feat) = %v, want ErrInvalidType", err)
+	}
+	if err := rules.Validate("fix(ui): align button"); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("Validate(fix(ui)) = %v, want ErrInvalidScope", err)
+	}
+
+	prompt := rules.BuildPrompt("diff", PromptOptions{})
+	for _, element := range []string{"- perf: Performance improvements", "perf(db): batch inserts", "Scope must be one of: db, api"} {
+		if !strings.Contains(prompt, element) {
+			t.Errorf("prompt missing %q", element)
+		}
+	}
+	if strings.Contains(prompt, "feat:") {
+		t.Error("prompt should only list the configured types")
+	}
+}
+
+func TestCaseRule(t *testing.T) {
+	never := CaseRule{Cases: []string{"sentence-case", "start-case", "pascal-case", "upper-case"}, Never: true}
+	for text, want := range map[string]bool{
+		"add retries":     true,
+		"Add retries":     false,
+		"Add Retries":     false,
+		"ADD RETRIES":     false,
+		"support OAuth 2": true,
+	} {
+		if got := never.Allows(text); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	lower := CaseRule{Cases: []string{"lower-case"}}
+	if !lower.Allows("feat") || lower.Allows("Feat") {
+		t.Error("lower-case rule should accept feat and reject Feat")
+	}
+}
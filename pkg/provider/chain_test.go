@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// scriptedProvider returns the queued errors in order, then succeeds.
+type scriptedProvider struct {
+	name  string
+	errs  []error
+	calls int
+}
+
+func (s *scriptedProvider) Name() string { return s.name }
+
+func (s *scriptedProvider) Capabilities() Capabilities { return Capabilities{} }
+
+func (s *scriptedProvider) Generate(ctx context.Context, _ string) (Result, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		if err := s.errs[s.calls-1]; err != nil {
+			return Result{}, err
+		}
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}
+	return Result{Provider: s.name, Text: "feat: from " + s.name}, nil
+}
+
+func newTestChain(providers ...Provider) (*Chain, *[]time.Duration) {
+	var delays []time.Duration
+	chain := NewChain(providers, RetryPolicy{Retries: 2, Backoff: time.Second, MaxBackoff: 3 * time.Second}, 0)
+	chain.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return chain, &delays
+}
+
+func TestChainRetriesTransientErrors(t *testing.T) {
+	primary := &scriptedProvider{name: "primary", errs: []error{
+		&HTTPError{StatusCode: http.StatusServiceUnavailable},
+		&HTTPError{StatusCode: http.StatusTooManyRequests},
+	}}
+	chain, delays := newTestChain(primary)
+
+	result, err := chain.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Provider != "primary" || primary.calls != 3 {
+		t.Errorf("got provider %q after %d calls, want primary after 3", result.Provider, primary.calls)
+	}
+	if len(*delays) != 2 || (*delays)[0] != time.Second || (*delays)[1] != 2*time.Second {
+		t.Errorf("backoff delays = %v, want [1s 2s]", *delays)
+	}
+}
+
+func TestChainFallsBackOnPermanentError(t *testing.T) {
+	primary := &scriptedProvider{name: "primary", errs: []error{&HTTPError{StatusCode: http.StatusUnauthorized}}}
+	secondary := &scriptedProvider{name: "secondary"}
+	chain, delays := newTestChain(primary, secondary)
+
+	result, err := chain.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Provider != "secondary" {
+		t.Errorf("Provider = %q, want secondary", result.Provider)
+	}
+	if primary.calls != 1 || len(*delays) != 0 {
+		t.Errorf("permanent error was retried: %d calls, delays %v", primary.calls, *delays)
+	}
+}
+
+func TestChainReportsAllFailures(t *testing.T) {
+	empty := &scriptedProvider{name: "empty", errs: []error{ErrEmptyResponse, ErrEmptyResponse, ErrEmptyResponse}}
+	missing := &scriptedProvider{name: "missing", errs: []error{ErrMissingAPIKey}}
+	chain, _ := newTestChain(empty, missing)
+
+	_, err := chain.Generate(context.Background(), "prompt")
+	if !errors.Is(err, ErrEmptyResponse) || !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Generate error = %v, want both provider failures", err)
+	}
+	if empty.calls != 3 {
+		t.Errorf("empty provider called %d times, want 3", empty.calls)
+	}
+}
+
+func TestChainTimeoutFallsBackWithoutRetry(t *testing.T) {
+	// A nil queued error makes the provider block until its context expires.
+	hung := &scriptedProvider{name: "hung", errs: []error{nil}}
+	secondary := &scriptedProvider{name: "secondary"}
+	chain, _ := newTestChain(hung, secondary)
+	chain.timeout = 10 * time.Millisecond
+
+	var notes []string
+	chain.Notify = func(format string, _ ...any) { notes = append(notes, format) }
+
+	result, err := chain.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Provider != "secondary" || hung.calls != 1 {
+		t.Errorf("got %q after %d hung calls, want secondary after 1", result.Provider, hung.calls)
+	}
+	if len(notes) != 1 {
+		t.Errorf("expected one failure notification, got %d", len(notes))
+	}
+}
+
+func TestChainStartingAtRotatesProviders(t *testing.T) {
+	first := &scriptedProvider{name: "first"}
+	second := &scriptedProvider{name: "second"}
+	chain, _ := newTestChain(first, second)
+
+	if got := chain.StartingAt(1).Name(); got != "second,first" {
+		t.Errorf("StartingAt(1).Name() = %q, want second,first", got)
+	}
+	if got := chain.StartingAt(2).Name(); got != "first,second" {
+		t.Errorf("StartingAt(2).Name() = %q, want first,second", got)
+	}
+	if got := chain.Name(); got != "first,second" {
+		t.Errorf("original chain changed to %q", got)
+	}
+}
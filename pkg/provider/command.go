@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// processKillDelay is how long a cancelled provider command may take to exit
+// before it is killed outright.
+const processKillDelay = 3 * time.Second
+
+// Names of the built-in CLI providers.
+const (
+	NameClaude  = "claude"
+	NameGemini  = "gemini"
+	NameCopilot = "copilot"
+)
+
+// Placeholders that a command template may use in its arguments.
+const (
+	PlaceholderPrompt     = "{prompt}"
+	PlaceholderPromptFile = "{prompt_file}"
+)
+
+// InputMode selects how the prompt is handed to a provider command.
+type InputMode string
+
+// Supported input modes.
+const (
+	InputStdin InputMode = "stdin"
+	InputArg   InputMode = "arg"
+	InputFile  InputMode = "file"
+)
+
+// CommandSpec describes a CLI provider: the argv template, how the prompt is
+// passed and an optional regular expression that extracts the answer from stdout.
+type CommandSpec struct {
+	Argv  []string
+	Input InputMode
+	// Extract is matched against stdout; the first capture group (or the whole
+	// match when there is none) becomes the result.
+	Extract string
+}
+
+// ResolveCommand returns the executable for a CLI provider name and whether it is a Claude provider.
+// Any name starting with "claude" (claude-external, claude-2, ...) runs the claude binary.
+func ResolveCommand(name string) (string, bool) {
+	switch {
+	case strings.HasPrefix(name, NameClaude):
+		return NameClaude, true
+	case name == NameGemini:
+		return NameGemini, false
+	case name == NameCopilot:
+		return NameCopilot, false
+	default:
+		return "", false
+	}
+}
+
+// Command runs a local AI CLI and reads the answer from its stdout.
+type Command struct {
+	name    string
+	argv    []string
+	input   InputMode
+	extract *regexp.Regexp
+	verbose io.Writer
+}
+
+// NewCommand returns a provider that executes command with the prompt on stdin.
+func NewCommand(name, command string, opts Options) *Command {
+	return &Command{name: name, argv: []string{command}, input: InputStdin, verbose: opts.Verbose}
+}
+
+// NewCommandFromSpec returns a provider for a user-defined command template.
+func NewCommandFromSpec(name string, spec CommandSpec, opts Options) (*Command, error) {
+	if len(spec.Argv) == 0 || spec.Argv[0] == "" {
+		return nil, fmt.Errorf("%w: provider %q has an empty command", ErrInvalidCommand, name)
+	}
+
+	input := spec.Input
+	switch input {
+	case "":
+		input = InputStdin
+	case InputStdin, InputArg, InputFile:
+	default:
+		return nil, fmt.Errorf("%w: provider %q has unknown input %q (want stdin, arg or file)", ErrInvalidCommand, name, input)
+	}
+
+	command := &Command{name: name, argv: spec.Argv, input: input, verbose: opts.Verbose}
+	if spec.Extract != "" {
+		extract, err := regexp.Compile(spec.Extract)
+		if err != nil {
+			return nil, fmt.Errorf("%w: provider %q has an invalid extract pattern: %w", ErrInvalidCommand, name, err)
+		}
+		command.extract = extract
+	}
+	return command, nil
+}
+
+// CommandFactory returns a registry factory for a user-defined command template.
+func CommandFactory(spec CommandSpec) Factory {
+	return func(name string, opts Options) (Provider, error) {
+		return NewCommandFromSpec(name, spec, opts)
+	}
+}
+
+func newCommandFactory(name string, opts Options) (Provider, error) {
+	command, _ := ResolveCommand(name)
+	if command == "" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedProvider, name)
+	}
+	return NewCommand(name, command, opts), nil
+}
+
+// Name returns the provider name as selected by the user.
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Capabilities reports that the command provider has no configurable options.
+func (c *Command) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// Generate executes the provider command with the prompt passed as configured. Stderr is
+// kept for the error message and, in verbose mode, echoed as it arrives.
+func (c *Command) Generate(ctx context.Context, prompt string) (Result, error) {
+	argv, cleanup, err := c.buildArgv(prompt)
+	if err != nil {
+		return Result{}, err
+	}
+	defer cleanup()
+
+	//nolint:gosec // G204: argv comes from a built-in provider or the user's own config
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if c.input == InputStdin {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+	configureProcess(cmd)
+
+	var stdout strings.Builder
+	stderr := newTailBuffer(maxStderr)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	if c.verbose != nil {
+		fmt.Fprintf(c.verbose, "$ %s\n", c.displayCommandLine(argv, prompt))
+		cmd.Stderr = io.MultiWriter(stderr, c.verbose)
+	}
+
+	start := time.Now()
+	err = cmd.Run()
+	duration := time.Since(start)
+	if c.verbose != nil {
+		fmt.Fprintf(c.verbose, "%s finished in %s\n", c.name, duration.Round(time.Millisecond))
+	}
+	if err != nil {
+		return Result{}, &CommandError{
+			Provider:    c.name,
+			CommandLine: c.displayCommandLine(argv, prompt),
+			Duration:    duration,
+			Stderr:      stderr.String(),
+			Err:         err,
+		}
+	}
+
+	return Result{Provider: c.name, Text: c.extractAnswer(stdout.String())}, nil
+}
+
+// buildArgv expands the template placeholders. For arg and file input the prompt
+// (or the temp file path) is appended when the template has no placeholder for it.
+func (c *Command) buildArgv(prompt string) ([]string, func(), error) {
+	cleanup := func() {}
+	value, placeholder := "", ""
+
+	switch c.input {
+	case InputArg:
+		value, placeholder = prompt, PlaceholderPrompt
+	case InputFile:
+		file, err := os.CreateTemp("", "commitgen-prompt-*.txt")
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to create prompt file: %w", err)
+		}
+		cleanup = func() { _ = os.Remove(file.Name()) }
+		if _, err := file.WriteString(prompt); err != nil {
+			_ = file.Close()
+			cleanup()
+			return nil, func() {}, fmt.Errorf("failed to write prompt file: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("failed to write prompt file: %w", err)
+		}
+		value, placeholder = file.Name(), PlaceholderPromptFile
+	case InputStdin:
+		return c.argv, cleanup, nil
+	}
+
+	argv := make([]string, 0, len(c.argv)+1)
+	replaced := false
+	for _, arg := range c.argv {
+		if strings.Contains(arg, placeholder) {
+			arg = strings.ReplaceAll(arg, placeholder, value)
+			replaced = true
+		}
+		argv = append(argv, arg)
+	}
+	if !replaced {
+		argv = append(argv, value)
+	}
+	return argv, cleanup, nil
+}
+
+// displayCommandLine renders argv without dumping the whole prompt into error messages.
+func (c *Command) displayCommandLine(argv []string, prompt string) string {
+	if c.input != InputArg || prompt == "" {
+		return formatCommandLine(argv)
+	}
+	shown := make([]string, len(argv))
+	for i, arg := range argv {
+		shown[i] = strings.ReplaceAll(arg, prompt, "<prompt>")
+	}
+	return formatCommandLine(shown)
+}
+
+// extractAnswer applies the extract pattern to stdout, falling back to the whole output.
+func (c *Command) extractAnswer(output string) string {
+	if c.extract == nil {
+		return strings.TrimSpace(output)
+	}
+	match := c.extract.FindStringSubmatch(output)
+	switch {
+	case match == nil:
+		return ""
+	case len(match) > 1:
+		return strings.TrimSpace(match[1])
+	default:
+		return strings.TrimSpace(match[0])
+	}
+}
@@ -0,0 +1,140 @@
+//go:build unix
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandCancelStopsProcessGroup(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "hang")
+	// The child sleep keeps stdout open, so only a group kill lets Generate return.
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 30 &\nwait\n"), 0o700); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewCommand("hang", script, Options{}).Generate(ctx, "prompt")
+	if err == nil {
+		t.Fatal("Generate should fail when the context expires")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("context error = %v, want deadline exceeded", ctx.Err())
+	}
+	if elapsed := time.Since(start); elapsed > processKillDelay {
+		t.Errorf("Generate returned after %s, want prompt termination", elapsed)
+	}
+}
+
+func TestCommandErrorIncludesStderr(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "fail")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'quota exceeded' >&2\nexit 3\n"), 0o700); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	var verbose strings.Builder
+	_, err := NewCommand("fail", script, Options{Verbose: &verbose}).Generate(context.Background(), "prompt")
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Generate error = %v, want *CommandError", err)
+	}
+	if cmdErr.Stderr != "quota exceeded" || cmdErr.CommandLine != script {
+		t.Errorf("CommandError = %+v, want stderr and command line", cmdErr)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Generate error should unwrap to exit status 3, got %v", err)
+	}
+	if !strings.Contains(verbose.String(), "quota exceeded") {
+		t.Errorf("verbose output %q should echo stderr", verbose.String())
+	}
+}
+
+func TestChainTimeoutKeepsCommandError(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "slow")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'still thinking' >&2\nexec sleep 30\n"), 0o700); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	chain := NewChain([]Provider{NewCommand("slow", script, Options{})}, RetryPolicy{}, 200*time.Millisecond)
+	_, err := chain.Generate(context.Background(), "prompt")
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Generate error = %v, want ErrTimeout", err)
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.CommandLine != script || cmdErr.Stderr != "still thinking" {
+		t.Errorf("Generate error = %v, want the command line and stderr kept", err)
+	}
+}
+
+func TestCommandTemplateInputModes(t *testing.T) {
+	tests := []struct {
+		name string
+		spec CommandSpec
+	}{
+		{"stdin", CommandSpec{Argv: []string{"sh", "-c", "cat"}}},
+		{"arg placeholder", CommandSpec{Argv: []string{"sh", "-c", `printf '%s' "$1"`, "sh", "{prompt}"}, Input: InputArg}},
+		{"arg appended", CommandSpec{Argv: []string{"sh", "-c", `printf '%s' "$1"`, "sh"}, Input: InputArg}},
+		{"file", CommandSpec{Argv: []string{"sh", "-c", `cat "$1"`, "sh", "{prompt_file}"}, Input: InputFile}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			command, err := NewCommandFromSpec(test.name, test.spec, Options{})
+			if err != nil {
+				t.Fatalf("NewCommandFromSpec returned error: %v", err)
+			}
+			result, err := command.Generate(context.Background(), "feat(cli): add templates")
+			if err != nil {
+				t.Fatalf("Generate returned error: %v", err)
+			}
+			if result.Text != "feat(cli): add templates" {
+				t.Errorf("Text = %q, want the prompt echoed back", result.Text)
+			}
+		})
+	}
+}
+
+func TestCommandTemplateExtract(t *testing.T) {
+	spec := CommandSpec{
+		Argv:    []string{"sh", "-c", "echo 'Suggestion:'; echo '  # fix(api): retry on 503'; echo done"},
+		Extract: `#\s*(\S.*)`,
+	}
+	command, err := NewCommandFromSpec("extract", spec, Options{})
+	if err != nil {
+		t.Fatalf("NewCommandFromSpec returned error: %v", err)
+	}
+
+	result, err := command.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Text != "fix(api): retry on 503" {
+		t.Errorf("Text = %q, want the extracted capture group", result.Text)
+	}
+}
+
+func TestCommandTemplateValidation(t *testing.T) {
+	invalid := []CommandSpec{
+		{},
+		{Argv: []string{"llm"}, Input: "pipe"},
+		{Argv: []string{"llm"}, Extract: "("},
+	}
+	for _, spec := range invalid {
+		if _, err := NewCommandFromSpec("bad", spec, Options{}); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("NewCommandFromSpec(%+v) error = %v, want ErrInvalidCommand", spec, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxStderr limits how much provider stderr is kept for error messages. The tail
+// is kept because CLIs usually print the actual failure last.
+const maxStderr = 2048
+
+// CommandError describes a failed provider command with everything needed to reproduce it.
+type CommandError struct {
+	Provider    string
+	CommandLine string
+	Duration    time.Duration
+	Stderr      string
+	Err         error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("failed to call %s API: `%s` failed after %s: %v",
+		e.Provider, e.CommandLine, e.Duration.Round(time.Millisecond), e.Err)
+	if e.Stderr != "" {
+		msg += "\nstderr:\n" + e.Stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written to it.
+type tailBuffer struct {
+	max       int
+	data      []byte
+	truncated bool
+}
+
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{max: limit}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if overflow := len(b.data) - b.max; overflow > 0 {
+		b.data = b.data[overflow:]
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the kept output, starting at a line boundary when it was truncated.
+func (b *tailBuffer) String() string {
+	text := string(b.data)
+	if !b.truncated {
+		return strings.TrimSpace(text)
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return "[... earlier output omitted ...]\n" + strings.TrimSpace(text)
+}
+
+// formatCommandLine renders argv so it can be pasted into a POSIX shell.
+// Arguments with special characters are single-quoted, so the shell expands
+// nothing inside them.
+func formatCommandLine(argv []string) string {
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\r\n\"'\\$`|&;<>()*?[]{}~#!") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
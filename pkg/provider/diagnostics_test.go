@@ -0,0 +1,27 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTailBufferKeepsLastLines(t *testing.T) {
+	buffer := newTailBuffer(16)
+	_, _ = buffer.Write([]byte("first line\nsecond\nlast line\n"))
+
+	got := buffer.String()
+	if !strings.HasPrefix(got, "[... earlier output omitted ...]\n") {
+		t.Errorf("String() = %q, want truncation marker", got)
+	}
+	if !strings.HasSuffix(got, "last line") || strings.Contains(got, "first") {
+		t.Errorf("String() = %q, want only the tail starting at a line boundary", got)
+	}
+}
+
+func TestFormatCommandLine(t *testing.T) {
+	got := formatCommandLine([]string{"claude", "-p", "--model", "my model", "", "it's $HOME\t`x`"})
+	want := `claude -p --model 'my model' '' 'it'\''s $HOME` + "\t" + "`x`'"
+	if got != want {
+		t.Errorf("formatCommandLine = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBody limits how much of a failed response body is kept in an HTTPError.
+const maxErrorBody = 1024
+
+// HTTPError is returned when a provider endpoint answers with a non-2xx status.
+type HTTPError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s API returned HTTP %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API returned HTTP %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// httpTransport holds what every HTTP provider needs to make a request.
+type httpTransport struct {
+	provider string
+	client   *http.Client
+	verbose  io.Writer
+}
+
+func newHTTPTransport(providerName string, opts Options) httpTransport {
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return httpTransport{provider: providerName, client: client, verbose: opts.Verbose}
+}
+
+// postJSON sends payload as JSON to url and decodes a successful response into out.
+func (t httpTransport) postJSON(ctx context.Context, url string, headers map[string]string, payload, out any) error {
+	providerName := t.provider
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", providerName, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", providerName, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s API after %s: %w", providerName, time.Since(start).Round(time.Millisecond), err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if t.verbose != nil {
+		fmt.Fprintf(t.verbose, "POST %s -> %s in %s\n", url, resp.Status, time.Since(start).Round(time.Millisecond))
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &HTTPError{Provider: providerName, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(errBody))}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", providerName, err)
+	}
+	return nil
+}
+
+// joinURL appends path to base without doubling or dropping the separating slash.
+func joinURL(base, path string) string {
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// firstNonEmpty returns the first value that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
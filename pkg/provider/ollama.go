@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// NameOllama selects the local Ollama provider.
+const NameOllama = "ollama"
+
+// Ollama defaults, overridable through Options or the environment.
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "llama3.2"
+	// ollamaPromptTokens fits Ollama's default context window with room for the answer.
+	ollamaPromptTokens = 3000
+)
+
+// Ollama calls a local Ollama server so the diff never leaves the machine.
+type Ollama struct {
+	name        string
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature *float64
+	transport   httpTransport
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"` //nolint:tagliatelle // field name fixed by the Ollama API
+}
+
+type ollamaRequest struct {
+	Model   string        `json:"model"`
+	Prompt  string        `json:"prompt"`
+	Stream  bool          `json:"stream"`
+	Options ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Response string `json:"response"`
+}
+
+// NewOllama returns an Ollama provider. Empty options fall back to OLLAMA_HOST
+// and OLLAMA_MODEL, then to the built-in defaults.
+func NewOllama(name string, opts Options) *Ollama {
+	return &Ollama{
+		name:        name,
+		baseURL:     ollamaBaseURL(firstNonEmpty(opts.BaseURL, os.Getenv("OLLAMA_HOST"), DefaultOllamaBaseURL)),
+		model:       firstNonEmpty(opts.Model, os.Getenv("OLLAMA_MODEL"), DefaultOllamaModel),
+		maxTokens:   opts.MaxTokens,
+		temperature: opts.Temperature,
+		transport:   newHTTPTransport(name, opts),
+	}
+}
+
+func newOllamaFactory(name string, opts Options) (Provider, error) {
+	return NewOllama(name, opts), nil
+}
+
+// ollamaBaseURL accepts the bare host:port form that OLLAMA_HOST commonly uses.
+func ollamaBaseURL(host string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return "http://" + host
+}
+
+// Name returns the provider name as selected by the user.
+func (o *Ollama) Name() string {
+	return o.name
+}
+
+// Capabilities reports that Ollama is a configurable provider that keeps data local.
+func (o *Ollama) Capabilities() Capabilities {
+	return Capabilities{Local: true, Configurable: true, PromptTokens: ollamaPromptTokens}
+}
+
+// Generate runs a single non-streaming completion through /api/generate.
+func (o *Ollama) Generate(ctx context.Context, prompt string) (Result, error) {
+	request := ollamaRequest{
+		Model:  o.model,
+		Prompt: prompt,
+		Options: ollamaOptions{
+			Temperature: o.temperature,
+			NumPredict:  o.maxTokens,
+		},
+	}
+
+	var response ollamaResponse
+	if err := o.transport.postJSON(ctx, joinURL(o.baseURL, "/api/generate"), nil, request, &response); err != nil {
+		return Result{}, err
+	}
+
+	return Result{Provider: o.name, Text: strings.TrimSpace(response.Response)}, nil
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("path = %q, want /api/generate", r.URL.Path)
+		}
+
+		var request ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != "qwen2.5-coder" || request.Stream || request.Prompt != "prompt" {
+			t.Errorf("unexpected request: %+v", request)
+		}
+
+		_, _ = w.Write([]byte(`{"model":"qwen2.5-coder","response":"chore(deps): bump go modules\n","done":true}`))
+	}))
+	defer server.Close()
+
+	// OLLAMA_HOST is usually given without a scheme.
+	t.Setenv("OLLAMA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	ollama := NewOllama(NameOllama, Options{Model: "qwen2.5-coder"})
+
+	result, err := ollama.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Text != "chore(deps): bump go modules" {
+		t.Errorf("Text = %q, want trimmed response", result.Text)
+	}
+	if !ollama.Capabilities().Local {
+		t.Error("Ollama should report a local provider")
+	}
+}
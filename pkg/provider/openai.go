@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NameOpenAICompatible selects the provider for any OpenAI-style chat completions endpoint.
+const NameOpenAICompatible = "openai-compatible"
+
+// OpenAI-compatible defaults, overridable through Options or the environment.
+const (
+	DefaultOpenAIBaseURL   = "https://api.openai.com/v1"
+	DefaultOpenAIModel     = "gpt-4o-mini"
+	DefaultOpenAIMaxTokens = 256
+)
+
+// OpenAICompatible posts the prompt to a /chat/completions endpoint such as
+// OpenAI itself, vLLM, LiteLLM or the llama.cpp server.
+type OpenAICompatible struct {
+	name        string
+	baseURL     string
+	apiKey      string
+	model       string
+	maxTokens   int
+	temperature *float64
+	transport   httpTransport
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"` //nolint:tagliatelle // field name fixed by the OpenAI API
+	Temperature *float64        `json:"temperature,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// NewOpenAICompatible returns a chat completions provider. The base URL is the
+// API root including the version segment (for example http://localhost:8000/v1).
+// Empty options fall back to OPENAI_API_KEY, OPENAI_BASE_URL and OPENAI_MODEL.
+// The API key is optional because many self-hosted gateways do not check it.
+func NewOpenAICompatible(name string, opts Options) *OpenAICompatible {
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultOpenAIMaxTokens
+	}
+
+	return &OpenAICompatible{
+		name:        name,
+		baseURL:     firstNonEmpty(opts.BaseURL, os.Getenv("OPENAI_BASE_URL"), DefaultOpenAIBaseURL),
+		apiKey:      firstNonEmpty(opts.APIKey, os.Getenv("OPENAI_API_KEY")),
+		model:       firstNonEmpty(opts.Model, os.Getenv("OPENAI_MODEL"), DefaultOpenAIModel),
+		maxTokens:   maxTokens,
+		temperature: opts.Temperature,
+		transport:   newHTTPTransport(name, opts),
+	}
+}
+
+func newOpenAICompatibleFactory(name string, opts Options) (Provider, error) {
+	return NewOpenAICompatible(name, opts), nil
+}
+
+// Name returns the provider name as selected by the user.
+func (o *OpenAICompatible) Name() string {
+	return o.name
+}
+
+// Capabilities reports that the provider is a configurable network service.
+func (o *OpenAICompatible) Capabilities() Capabilities {
+	return Capabilities{Network: true, Configurable: true}
+}
+
+// Generate sends the prompt as a single user message and returns the first choice.
+func (o *OpenAICompatible) Generate(ctx context.Context, prompt string) (Result, error) {
+	request := openAIRequest{
+		Model:       o.model,
+		Messages:    []openAIMessage{{Role: "user", Content: prompt}},
+		MaxTokens:   o.maxTokens,
+		Temperature: o.temperature,
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	var response openAIResponse
+	if err := o.transport.postJSON(ctx, joinURL(o.baseURL, "/chat/completions"), headers, request, &response); err != nil {
+		return Result{}, err
+	}
+	if len(response.Choices) == 0 {
+		return Result{}, fmt.Errorf("%s API returned no choices: %w", o.name, ErrEmptyResponse)
+	}
+
+	return Result{Provider: o.name, Text: strings.TrimSpace(response.Choices[0].Message.Content)}, nil
+}
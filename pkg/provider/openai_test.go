@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompatibleGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %q, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer gateway-key" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+
+		var request openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != "mistral" || request.MaxTokens != 64 {
+			t.Errorf("unexpected request: %+v", request)
+		}
+		if request.Temperature == nil || *request.Temperature != 0 {
+			t.Errorf("Temperature = %v, want explicit 0", request.Temperature)
+		}
+
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" fix(api): handle empty body \n"}}]}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.0
+	openAI := NewOpenAICompatible(NameOpenAICompatible, Options{
+		BaseURL:     server.URL + "/v1",
+		APIKey:      "gateway-key",
+		Model:       "mistral",
+		MaxTokens:   64,
+		Temperature: &temperature,
+	})
+
+	result, err := openAI.Generate(context.Background(), "prompt")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if result.Text != "fix(api): handle empty body" {
+		t.Errorf("Text = %q, want trimmed first choice", result.Text)
+	}
+}
+
+func TestOpenAICompatibleNoChoices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Authorization header sent without an API key")
+		}
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "")
+	openAI := NewOpenAICompatible(NameOpenAICompatible, Options{BaseURL: server.URL})
+	if _, err := openAI.Generate(context.Background(), "prompt"); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Generate error = %v, want ErrEmptyResponse", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package provider
+
+import "os/exec"
+
+// configureProcess keeps the exec default of killing the process on cancellation;
+// process groups are not available on this platform.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.WaitDelay = processKillDelay
+}
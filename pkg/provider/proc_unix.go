@@ -0,0 +1,33 @@
+//go:build unix
+
+package provider
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureProcess runs cmd in its own process group. The terminal's Ctrl-C then
+// reaches only commitgen, and cancelling the context terminates the provider
+// together with any helpers it spawned instead of leaving them orphaned.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		// Give the group a moment to exit cleanly before forcing it.
+		time.AfterFunc(processKillDelay, func() {
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return nil
+	}
+	cmd.WaitDelay = processKillDelay
+}
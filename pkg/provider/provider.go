@@ -0,0 +1,60 @@
+// Package provider defines the AI backends commitgen can ask for a commit message.
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Define static errors for provider lookup and invocation.
+var (
+	ErrUnsupportedProvider = errors.New("unsupported provider")
+	ErrMissingAPIKey       = errors.New("missing API key")
+	ErrEmptyResponse       = errors.New("empty response")
+	ErrInvalidCommand      = errors.New("invalid provider command")
+)
+
+// Capabilities describes what a provider can do beyond plain generation.
+type Capabilities struct {
+	// Network is true when the provider talks to a remote service.
+	Network bool
+	// Local is true when the prompt never leaves the machine.
+	Local bool
+	// Configurable is true when the provider accepts model and endpoint options.
+	Configurable bool
+	// PromptTokens is the prompt size the provider handles well; 0 means no
+	// preference and the caller's default applies.
+	PromptTokens int
+}
+
+// Result holds the raw output of a single generation request.
+type Result struct {
+	Provider string
+	Text     string
+}
+
+// Options carries user settings that providers may honour when they are built.
+// Zero values mean "use the provider default"; providers ignore fields they do not support.
+type Options struct {
+	Model   string
+	BaseURL string
+	APIKey  string
+	// MaxTokens limits the length of the generated answer.
+	MaxTokens int
+	// Temperature is nil when the user did not ask for a specific value.
+	Temperature *float64
+	// HTTPClient is used by HTTP providers; nil selects http.DefaultClient.
+	HTTPClient *http.Client
+	// Verbose receives diagnostics (command lines, live stderr, HTTP status) as
+	// they happen; nil keeps providers quiet.
+	Verbose io.Writer
+}
+
+// Provider generates text for a prompt using some AI backend.
+type Provider interface {
+	Name() string
+	Generate(ctx context.Context, prompt string) (Result, error)
+	Capabilities() Capabilities
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory builds a provider for the requested name.
+type Factory func(name string, opts Options) (Provider, error)
+
+type registration struct {
+	name    string
+	factory Factory
+	prefix  bool
+}
+
+// Registry maps provider names to the factories that build them.
+type Registry struct {
+	mu            sync.RWMutex
+	registrations []registration
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider that is selected by its exact name.
+func (r *Registry) Register(name string, factory Factory) {
+	r.add(registration{name: name, factory: factory})
+}
+
+// RegisterPrefix adds a provider that is selected by any name starting with prefix.
+func (r *Registry) RegisterPrefix(prefix string, factory Factory) {
+	r.add(registration{name: prefix, factory: factory, prefix: true})
+}
+
+func (r *Registry) add(reg registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.registrations {
+		if existing.name == reg.name && existing.prefix == reg.prefix {
+			r.registrations[i] = reg
+			return
+		}
+	}
+	r.registrations = append(r.registrations, reg)
+}
+
+// New builds the provider registered for name.
+func (r *Registry) New(name string, opts Options) (Provider, error) {
+	factory, ok := r.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (available: %s)", ErrUnsupportedProvider, name, strings.Join(r.Names(), ", "))
+	}
+	return factory(name, opts)
+}
+
+// Names returns the registered provider names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.registrations))
+	for _, reg := range r.registrations {
+		if reg.prefix {
+			names = append(names, reg.name+"*")
+			continue
+		}
+		names = append(names, reg.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) lookup(name string) (Factory, bool) {
+	if name == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Exact names win over prefixes so a specific registration can shadow a family.
+	for _, reg := range r.registrations {
+		if !reg.prefix && reg.name == name {
+			return reg.factory, true
+		}
+	}
+	for _, reg := range r.registrations {
+		if reg.prefix && strings.HasPrefix(name, reg.name) {
+			return reg.factory, true
+		}
+	}
+	return nil, false
+}
+
+// Default is the registry used by the commitgen CLI.
+var Default = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.RegisterPrefix(NameClaude, newCommandFactory)
+	registry.Register(NameGemini, newCommandFactory)
+	registry.Register(NameCopilot, newCommandFactory)
+	registry.Register(NameAnthropic, newAnthropicFactory)
+	registry.Register(NameOpenAICompatible, newOpenAICompatibleFactory)
+	registry.Register(NameOllama, newOllamaFactory)
+	return registry
+}
+
+// New builds the named provider from the default registry.
+func New(name string, opts Options) (Provider, error) {
+	return Default.New(name, opts)
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type stubProvider struct {
+	name string
+}
+
+func (s stubProvider) Name() string { return s.name }
+
+func (s stubProvider) Capabilities() Capabilities { return Capabilities{} }
+
+func (s stubProvider) Generate(_ context.Context, _ string) (Result, error) {
+	return Result{Provider: s.name, Text: "feat: stub"}, nil
+}
+
+func TestDefaultRegistryResolvesBuiltins(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{NameClaude, NameClaude},
+		{"claude-external", NameClaude},
+		{NameGemini, NameGemini},
+		{NameCopilot, NameCopilot},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := New(test.name, Options{})
+			if err != nil {
+				t.Fatalf("New(%q) returned error: %v", test.name, err)
+			}
+			if p.Name() != test.name {
+				t.Errorf("Name() = %q, want %q", p.Name(), test.name)
+			}
+			cmd, ok := p.(*Command)
+			if !ok {
+				t.Fatalf("New(%q) = %T, want *Command", test.name, p)
+			}
+			if cmd.argv[0] != test.command {
+				t.Errorf("command = %q, want %q", cmd.argv[0], test.command)
+			}
+		})
+	}
+}
+
+func TestRegistryRejectsUnknownProvider(t *testing.T) {
+	for _, name := range []string{"", "openai", "claud", "cclaude"} {
+		if _, err := New(name, Options{}); !errors.Is(err, ErrUnsupportedProvider) {
+			t.Errorf("New(%q) error = %v, want ErrUnsupportedProvider", name, err)
+		}
+	}
+}
+
+func TestRegistryExactNameShadowsPrefix(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterPrefix("claude", func(name string, _ Options) (Provider, error) {
+		return stubProvider{name: "prefix"}, nil
+	})
+	registry.Register("claude-local", func(name string, _ Options) (Provider, error) {
+		return stubProvider{name: "exact"}, nil
+	})
+
+	p, err := registry.New("claude-local", Options{})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if p.Name() != "exact" {
+		t.Errorf("New(claude-local) resolved to %q, want exact registration", p.Name())
+	}
+
+	p, err = registry.New("claude-2", Options{})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if p.Name() != "prefix" {
+		t.Errorf("New(claude-2) resolved to %q, want prefix registration", p.Name())
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/commitrules"
+)
+
+// regenerateFunc asks the provider for a new message, optionally steered by user guidance.
+type regenerateFunc func(guidance string) (string, error)
+
+// reviewCommitMessage shows the message and lets the user accept, edit or regenerate it
+// until they accept (true) or decline (false). The lint findings are shown with every
+// version of the message, and a message with errors is only accepted after a second
+// confirmation.
+func reviewCommitMessage(ctx context.Context, commitMessage string, regenerate regenerateFunc) (string, bool, error) {
+	fmt.Printf("Generated commit message:\n\"%s\"\n\n", commitMessage)
+	hasErrors := showFindings(os.Stdout, commitMessage)
+
+	for {
+		fmt.Print("Use this commit message? [y]es / [e]dit / [r]egenerate / [n]o: ")
+		response, err := readLine(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println()
+				return "", false, ctx.Err()
+			}
+			// A closed stdin cannot confirm anything.
+			return "", false, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "y", "yes":
+			if !hasErrors {
+				return commitMessage, true, nil
+			}
+			confirmed, err := confirmErrors(ctx, commitMessage)
+			if err != nil {
+				return "", false, err
+			}
+			if confirmed {
+				return commitMessage, true, nil
+			}
+			continue
+		case "", "n", "no":
+			return "", false, nil
+		case "e", "edit":
+			edited, err := editMessage(ctx, commitMessage)
+			if err != nil {
+				printWarning("%v", err)
+				continue
+			}
+			if edited == "" {
+				fmt.Println("Empty message, keeping the previous one.")
+				continue
+			}
+			commitMessage = edited
+			fmt.Printf("Edited commit message:\n\"%s\"\n\n", commitMessage)
+		case "r", "regenerate":
+			fmt.Print("Extra guidance for the new message (optional): ")
+			guidance, err := readLine(ctx)
+			if err != nil && ctx.Err() != nil {
+				fmt.Println()
+				return "", false, ctx.Err()
+			}
+			regenerated, err := regenerate(guidance)
+			if err != nil {
+				printWarning("%v", err)
+				continue
+			}
+			commitMessage = regenerated
+			fmt.Printf("Regenerated commit message:\n\"%s\"\n\n", commitMessage)
+		default:
+			fmt.Println("Please answer y, e, r or n.")
+			continue
+		}
+		hasErrors = showFindings(os.Stdout, commitMessage)
+	}
+}
+
+// confirmErrors shows the errors in message again and asks whether to commit
+// it anyway. Anything but yes returns false.
+func confirmErrors(ctx context.Context, message string) (bool, error) {
+	fmt.Println("This message does not pass the commit rules:")
+	showFindings(os.Stdout, message)
+	fmt.Print("Commit it anyway? [y/N]: ")
+	response, err := readLine(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println()
+			return false, ctx.Err()
+		}
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// editMessage opens the user's git editor on the message and returns the result
+// with comment lines removed.
+func editMessage(ctx context.Context, message string) (string, error) {
+	return editText(ctx, message+"\n\n# Edit the commit message above. Lines starting with '#' are ignored.\n")
+}
+
+// editText opens the user's git editor on content and returns the result with
+// comment lines removed.
+func editText(ctx context.Context, content string) (string, error) {
+	editor, err := gitEditor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp("", "COMMITGEN_EDITMSG-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create message file: %w", err)
+	}
+	path := file.Name()
+	defer func() {
+		_ = os.Remove(path)
+	}()
+
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	// Run the editor through the shell like git does, so values such as
+	// "code --wait" work.
+	//nolint:gosec // G204: the editor is the user's own git editor setting
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$@"`, editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	//nolint:gosec // G304: path is the temp file created above
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+	return stripComments(string(edited)), nil
+}
+
+// gitEditor resolves the editor the same way git commit does:
+// GIT_EDITOR, core.editor, VISUAL, EDITOR, then git's built-in default.
+func gitEditor(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, "git", "var", "GIT_EDITOR").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine editor: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// scissorsLine marks the start of the diff that git commit --verbose appends.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// stripComments removes '#' comment lines, everything below a scissors line
+// and surrounding blank lines.
+func stripComments(message string) string {
+	lines := strings.Split(message, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.TrimRight(line, "\r") == scissorsLine {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// validateAndShowWarning reports lint findings on stderr so --print output stays clean.
+func validateAndShowWarning(commitMessage string) {
+	showFindings(os.Stderr, commitMessage)
+}
+
+// showFindings writes the lint findings for message to w and reports whether
+// any of them is an error.
+func showFindings(w io.Writer, message string) bool {
+	findings := commitrules.Lint(message)
+	for _, finding := range findings {
+		fmt.Fprintln(w, finding.String())
+	}
+	return commitrules.HasErrors(findings)
+}
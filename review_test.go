@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// withStdin replaces the prompt input for the duration of a test.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	previous := stdinLines
+	stdinLines = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinLines = previous })
+}
+
+func TestReviewCommitMessageRegenerateWithGuidance(t *testing.T) {
+	withStdin(t, "r\nmention the cache\ny\n")
+
+	var gotGuidance string
+	regenerate := func(guidance string) (string, error) {
+		gotGuidance = strings.TrimSpace(guidance)
+		return "feat(api): cache user lookups", nil
+	}
+
+	message, confirmed, err := reviewCommitMessage(context.Background(), "feat(api): speed up lookups", regenerate)
+	if err != nil || !confirmed {
+		t.Fatalf("reviewCommitMessage = %q, %v, %v; want confirmed", message, confirmed, err)
+	}
+	if gotGuidance != "mention the cache" {
+		t.Errorf("guidance = %q, want the user's text", gotGuidance)
+	}
+	if message != "feat(api): cache user lookups" {
+		t.Errorf("message = %q, want the regenerated one", message)
+	}
+}
+
+func TestReviewCommitMessageEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor is run through sh")
+	}
+
+	editor := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\nprintf 'fix(ui): align buttons\\n# comment\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatalf("failed to write editor: %v", err)
+	}
+	t.Setenv("GIT_EDITOR", editor)
+	withStdin(t, "maybe\ne\ny\n")
+
+	message, confirmed, err := reviewCommitMessage(context.Background(), "fix: stuff", nil)
+	if err != nil || !confirmed {
+		t.Fatalf("reviewCommitMessage = %q, %v, %v; want confirmed", message, confirmed, err)
+	}
+	if message != "fix(ui): align buttons" {
+		t.Errorf("message = %q, want the edited text without comments", message)
+	}
+}
+
+func TestReviewCommitMessageDeclines(t *testing.T) {
+	for _, input := range []string{"n\n", "\n", ""} {
+		withStdin(t, input)
+		_, confirmed, err := reviewCommitMessage(context.Background(), "feat: add x", nil)
+		if err != nil || confirmed {
+			t.Errorf("input %q: confirmed = %v, err = %v; want declined", input, confirmed, err)
+		}
+	}
+}
+
+func TestReviewCommitMessageConfirmsErrors(t *testing.T) {
+	regenerate := func(string) (string, error) { return "Added widgets", nil }
+
+	// Declining the second confirmation returns to the prompt.
+	withStdin(t, "r\n\ny\nn\nn\n")
+	if _, confirmed, err := reviewCommitMessage(context.Background(), "feat: add widgets", regenerate); err != nil || confirmed {
+		t.Errorf("confirmed = %v, err = %v; want the invalid message declined", confirmed, err)
+	}
+
+	withStdin(t, "y\ny\n")
+	message, confirmed, err := reviewCommitMessage(context.Background(), "Added widgets", regenerate)
+	if err != nil || !confirmed || message != "Added widgets" {
+		t.Errorf("reviewCommitMessage = %q, %v, %v; want the message committed after confirming", message, confirmed, err)
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/FreePeak/commitgen/pkg/changeset"
+	"github.com/urfave/cli/v2"
+)
+
+// ErrInvalidPlan is returned for an edited split plan that cannot be used.
+var ErrInvalidPlan = errors.New("invalid split plan")
+
+// nestedRoots are directories whose children are separate components, such as
+// pkg/config and pkg/provider.
+var nestedRoots = map[string]bool{
+	"apps": true, "cmd": true, "internal": true, "lib": true,
+	"packages": true, "pkg": true, "services": true, "src": true,
+}
+
+// planHelp is appended to the plan when the user edits it.
+const planHelp = `
+# Each "commit" line starts a commit with that message; the indented paths
+# below it go into the commit. Lines starting with "> " after the commit line
+# continue the message (body and footers). Paths with spaces, quotes or
+# control characters are written in double quotes with Go escapes.
+#
+# Move paths between commits, reword or reorder commits, or delete them.
+# Staged files left out of the plan stay staged. An empty plan cancels.
+`
+
+// splitCommit is one commit of a split plan.
+type splitCommit struct {
+	message string
+	paths   []string
+}
+
+func createSplitCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "split",
+		Usage:  "Split the staged changes into several commits, one per group of related files",
+		Flags:  generationFlags(),
+		Action: splitStagedChanges,
+	}
+}
+
+func splitStagedChanges(cliContext *cli.Context) error {
+	ctx := cliContext.Context
+	if !isGitRepo() {
+		return ErrNotGitRepo
+	}
+	if err := os.Chdir(getRepoRoot(ctx)); err != nil {
+		return fmt.Errorf("failed to change to the repository root: %w", err)
+	}
+
+	run, err := getRunOptions(cliContext)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadGenerationConfig(cliContext)
+	if err != nil {
+		return err
+	}
+
+	set, err := changeset.Collect(ctx, changeset.ModeStaged)
+	if err != nil {
+		return interruptedOr(ctx, fmt.Errorf("failed to get staged files: %w", err))
+	}
+	if len(set.Files) == 0 {
+		return ErrNoStagedFiles
+	}
+	// Both sides of a rename are staged paths, so a commit takes the deletion too.
+	staged := set.Paths()
+	// The staged state is saved as a tree so every commit takes its files from
+	// it, whatever happens to the index in between.
+	output, err := exec.CommandContext(ctx, "git", "write-tree").Output()
+	if err != nil {
+		return fmt.Errorf("failed to save the staged changes: %w", err)
+	}
+	tree := strings.TrimSpace(string(output))
+
+	settings := getGenerationSettings(cliContext, cfg)
+	files := make([]string, len(set.Files))
+	for i, file := range set.Files {
+		files[i] = file.Path
+	}
+	groups := groupPaths(files)
+	plan := make([]splitCommit, len(groups))
+	for i, group := range groups {
+		fmt.Fprintf(os.Stderr, "Generating message %d of %d...\n", i+1, len(groups))
+		part := set.Select(group)
+		analysisInput := changeset.Render(part, settings.analysisTokens())
+		message, err := callAIAPI(ctx, settings.prompt(analysisInput, ""), settings)
+		if err != nil {
+			return interruptedOr(ctx, fmt.Errorf("failed to generate commit message: %w", err))
+		}
+		plan[i] = splitCommit{message: settings.clean(message), paths: part.Paths()}
+	}
+
+	switch {
+	case run.printOnly:
+		fmt.Print(formatPlan(plan))
+		return nil
+	case run.dryRun:
+		fmt.Print(formatPlan(plan))
+		fmt.Println("\nDry run: nothing was committed.")
+		return nil
+	case !run.yes:
+		var confirmed bool
+		plan, confirmed, err = reviewSplitPlan(ctx, plan, staged)
+		if err != nil {
+			return interruptedOr(ctx, err)
+		}
+		if !confirmed {
+			fmt.Println("Split cancelled.")
+			return nil
+		}
+	}
+
+	return interruptedOr(ctx, commitSplitPlan(ctx, tree, plan))
+}
+
+// groupPaths groups files by component: the top-level directory, or the first
+// two levels under directories such as pkg/ and cmd/. Files at the repository
+// root form one group. Groups are in the order their first file appears.
+func groupPaths(paths []string) [][]string {
+	var groups [][]string
+	index := map[string]int{}
+	for _, path := range paths {
+		component := componentOf(path)
+		i, ok := index[component]
+		if !ok {
+			i = len(groups)
+			index[component] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], path)
+	}
+	return groups
+}
+
+func componentOf(path string) string {
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 1:
+		return ""
+	case nestedRoots[parts[0]] && len(parts) > 2:
+		return parts[0] + "/" + parts[1]
+	default:
+		return parts[0]
+	}
+}
+
+// formatPlan writes the plan in the format parsePlan reads.
+func formatPlan(plan []splitCommit) string {
+	var b strings.Builder
+	for i, c := range plan {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		header, body, _ := strings.Cut(c.message, "\n")
+		fmt.Fprintf(&b, "commit %s\n", header)
+		if body = strings.TrimSpace(body); body != "" {
+			for _, line := range strings.Split(body, "\n") {
+				b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+		}
+		for _, path := range c.paths {
+			fmt.Fprintf(&b, "    %s\n", changeset.QuotePath(path))
+		}
+	}
+	return b.String()
+}
+
+// parsePlan reads an edited plan. Every path must be one of the staged files
+// and may appear only once.
+func parsePlan(text string, staged []string) ([]splitCommit, error) {
+	known := make(map[string]bool, len(staged))
+	for _, path := range staged {
+		known[path] = true
+	}
+	used := map[string]bool{}
+
+	var plan []splitCommit
+	var body []string
+	finish := func() {
+		if len(plan) > 0 && len(body) > 0 {
+			last := &plan[len(plan)-1]
+			last.message += "\n\n" + strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = nil
+	}
+
+	for n, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "commit "):
+			finish()
+			message := strings.TrimSpace(strings.TrimPrefix(line, "commit "))
+			if message == "" {
+				return nil, fmt.Errorf("%w: line %d: commit without a message", ErrInvalidPlan, n+1)
+			}
+			plan = append(plan, splitCommit{message: message})
+		case strings.HasPrefix(line, ">"):
+			if len(plan) == 0 {
+				return nil, fmt.Errorf("%w: line %d: message line before the first commit", ErrInvalidPlan, n+1)
+			}
+			body = append(body, strings.TrimPrefix(strings.TrimPrefix(line, ">"), " "))
+		case line[0] == ' ' || line[0] == '\t':
+			path := strings.TrimSpace(line)
+			if strings.HasPrefix(path, `"`) {
+				unquoted, err := strconv.Unquote(path)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: bad quoted path %s", ErrInvalidPlan, n+1, path)
+				}
+				path = unquoted
+			}
+			switch {
+			case len(plan) == 0:
+				return nil, fmt.Errorf("%w: line %d: path before the first commit", ErrInvalidPlan, n+1)
+			case !known[path]:
+				return nil, fmt.Errorf("%w: line %d: %s is not staged", ErrInvalidPlan, n+1, path)
+			case used[path]:
+				return nil, fmt.Errorf("%w: line %d: %s is in more than one commit", ErrInvalidPlan, n+1, path)
+			}
+			used[path] = true
+			plan[len(plan)-1].paths = append(plan[len(plan)-1].paths, path)
+		default:
+			return nil, fmt.Errorf("%w: line %d: expected \"commit <message>\" or an indented path", ErrInvalidPlan, n+1)
+		}
+	}
+	finish()
+
+	for _, c := range plan {
+		if len(c.paths) == 0 {
+			return nil, fmt.Errorf("%w: commit %q has no files", ErrInvalidPlan, firstLine(c.message))
+		}
+	}
+	return plan, nil
+}
+
+// reviewSplitPlan shows the plan and lets the user accept, edit or cancel it.
+func reviewSplitPlan(ctx context.Context, plan []splitCommit, staged []string) ([]splitCommit, bool, error) {
+	for {
+		fmt.Printf("Proposed commits:\n\n%s\n", formatPlan(plan))
+		if left := len(staged) - countPaths(plan); left > 0 {
+			fmt.Printf("%d staged file(s) are not in the plan and stay staged.\n\n", left)
+		}
+
+		fmt.Print("Create these commits? [y]es / [e]dit / [n]o: ")
+		response, err := readLine(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Println()
+				return nil, false, ctx.Err()
+			}
+			return nil, false, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "y", "yes":
+			return plan, true, nil
+		case "", "n", "no":
+			return nil, false, nil
+		case "e", "edit":
+			edited, err := editText(ctx, formatPlan(plan)+planHelp)
+			if err != nil {
+				printWarning("%v", err)
+				continue
+			}
+			if edited == "" {
+				return nil, false, nil
+			}
+			edit, err := parsePlan(edited, staged)
+			if err != nil {
+				printWarning("%v", err)
+				continue
+			}
+			plan = edit
+		default:
+			fmt.Println("Please answer y, e or n.")
+		}
+	}
+}
+
+func countPaths(plan []splitCommit) int {
+	n := 0
+	for _, c := range plan {
+		n += len(c.paths)
+	}
+	return n
+}
+
+// commitSplitPlan creates the planned commits in order. Each commit is built in
+// a temporary index holding HEAD plus the staged version of its files, taken
+// from tree, so the user's index is never rewritten: once a commit is made its
+// files simply match HEAD, and if a commit fails the rest stays staged.
+func commitSplitPlan(ctx context.Context, tree string, plan []splitCommit) error {
+	dir, err := os.MkdirTemp("", "commitgen-split-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(dir, "index"), "GIT_LITERAL_PATHSPECS=1")
+
+	for i, c := range plan {
+		base := []string{"read-tree", "HEAD"}
+		if exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD").Run() != nil {
+			// The first commit of a repository.
+			base = []string{"read-tree", "--empty"}
+		}
+		if err := runGit(ctx, env, "", base...); err != nil {
+			return err
+		}
+		paths := strings.Join(c.paths, "\x00") + "\x00"
+		if err := runGit(ctx, env, paths, "reset", "-q", tree, "--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+			return err
+		}
+		if err := runGit(ctx, env, c.message+"\n", "commit", "-q", "-F", "-"); err != nil {
+			return fmt.Errorf("commit %d of %d: %w", i+1, len(plan), err)
+		}
+		fmt.Printf("Committed %d/%d: %s\n", i+1, len(plan), firstLine(c.message))
+	}
+	return nil
+}
+
+// runGit runs git with env and stdin, including git's error output in the error.
+func runGit(ctx context.Context, env []string, stdin string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupPaths(t *testing.T) {
+	paths := []string{"pkg/config/config.go", "README.md", "pkg/provider/chain.go", "main.go", "pkg/config/keys.go", "docs/a.md"}
+	want := [][]string{
+		{"pkg/config/config.go", "pkg/config/keys.go"},
+		{"README.md", "main.go"},
+		{"pkg/provider/chain.go"},
+		{"docs/a.md"},
+	}
+	if got := groupPaths(paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupPaths = %v, want %v", got, want)
+	}
+}
+
+func TestParsePlanRoundTrip(t *testing.T) {
+	staged := []string{"a.go", "b.go", "docs/c.md", "docs/Design (v2).md", " padded\n\"name\".md"}
+	plan := []splitCommit{
+		{message: "feat(api): add widgets\n\nWidgets were requested.\n\nRefs: #12", paths: []string{"a.go", "b.go"}},
+		{message: "docs: describe widgets", paths: []string{"docs/c.md", "docs/Design (v2).md", " padded\n\"name\".md"}},
+	}
+
+	got, err := parsePlan(formatPlan(plan)+planHelp, staged)
+	if err != nil {
+		t.Fatalf("parsePlan returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, plan) {
+		t.Errorf("parsePlan = %+v, want %+v", got, plan)
+	}
+}
+
+func TestParsePlanRejectsBadPlans(t *testing.T) {
+	staged := []string{"a.go", "b.go"}
+	tests := map[string]string{
+		"unknown path":    "commit fix: x\n    c.go\n",
+		"duplicate path":  "commit fix: x\n    a.go\ncommit fix: y\n    a.go\n",
+		"empty commit":    "commit fix: x\ncommit fix: y\n    a.go\n",
+		"path first":      "    a.go\ncommit fix: x\n",
+		"unexpected line": "commit fix: x\na.go\n",
+	}
+	for name, text := range tests {
+		if _, err := parsePlan(text, staged); !errors.Is(err, ErrInvalidPlan) {
+			t.Errorf("%s: parsePlan error = %v, want ErrInvalidPlan", name, err)
+		}
+	}
+}
+
+func TestCommitSplitPlan(t *testing.T) {
+	dir := initRepo(t)
+	for _, name := range []string{"a.go", "docs/b.md", "docs/c.md"} {
+		writeTestFile(t, filepath.Join(dir, name))
+	}
+	if output, err := exec.Command("git", "add", ".").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, output)
+	}
+	tree, err := exec.Command("git", "write-tree").Output()
+	if err != nil {
+		t.Fatalf("git write-tree failed: %v", err)
+	}
+
+	plan := []splitCommit{
+		{message: "feat: add a", paths: []string{"a.go"}},
+		{message: "docs: add b", paths: []string{"docs/b.md"}},
+	}
+	if err := commitSplitPlan(context.Background(), strings.TrimSpace(string(tree)), plan); err != nil {
+		t.Fatalf("commitSplitPlan returned error: %v", err)
+	}
+
+	log, _ := exec.Command("git", "log", "--format=%s", "--name-only", "-2").Output()
+	if want := "docs: add b\n\ndocs/b.md\nfeat: add a\n\na.go\n"; string(log) != want {
+		t.Errorf("log = %q, want %q", log, want)
+	}
+	status, _ := exec.Command("git", "status", "--porcelain").Output()
+	if string(status) != "A  docs/c.md\n" {
+		t.Errorf("status = %q, want only the file left out of the plan staged", status)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FreePeak/commitgen/pkg/changeset"
+)
+
+// initRepo creates a git repository with one commit and makes it the working
+// directory for the rest of the test.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(previous) })
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "chore: initial commit"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestStagePathsStagesExactlyThePaths(t *testing.T) {
+	dir := initRepo(t)
+	for _, name := range []string{"a [1].txt", "a 1.txt", "*.txt", "sub/b.txt"} {
+		writeTestFile(t, filepath.Join(dir, name))
+	}
+	// Stage from a subdirectory to check paths are taken from the repository root.
+	if err := os.Chdir(filepath.Join(dir, "sub")); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	if err := stagePaths(context.Background(), []string{"*.txt", "sub/b.txt"}); err != nil {
+		t.Fatalf("stagePaths returned error: %v", err)
+	}
+	output, err := exec.Command("git", "diff", "--cached", "--name-only", "-z").Output()
+	if err != nil {
+		t.Fatalf("git diff failed: %v", err)
+	}
+	if got := strings.Split(strings.TrimRight(string(output), "\x00"), "\x00"); strings.Join(got, ",") != "*.txt,sub/b.txt" {
+		t.Errorf("staged = %q, want only the literal paths", got)
+	}
+}
+
+func TestExecuteCommitLeavesEarlierStagedFiles(t *testing.T) {
+	for _, mode := range []string{"untracked", "all"} {
+		t.Run(mode, func(t *testing.T) {
+			dir := initRepo(t)
+			git := func(args ...string) string {
+				t.Helper()
+				output, err := exec.Command("git", args...).CombinedOutput()
+				if err != nil {
+					t.Fatalf("git %v failed: %v\n%s", args, err, output)
+				}
+				return string(output)
+			}
+			writeTestFile(t, filepath.Join(dir, "tracked.txt"))
+			writeTestFile(t, filepath.Join(dir, "gone.txt"))
+			git("add", ".")
+			git("commit", "-q", "-m", "chore: add files")
+
+			writeTestFile(t, filepath.Join(dir, "pre.txt"))
+			git("add", "pre.txt")
+			if err := os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("changed\n"), 0o600); err != nil {
+				t.Fatalf("failed to change tracked.txt: %v", err)
+			}
+			if err := os.Remove(filepath.Join(dir, "gone.txt")); err != nil {
+				t.Fatalf("failed to remove gone.txt: %v", err)
+			}
+			writeTestFile(t, filepath.Join(dir, "new file.txt"))
+
+			ctx := context.Background()
+			_, paths, err := getAnalysisInput(ctx, mode, 8000)
+			if err != nil {
+				t.Fatalf("getAnalysisInput returned error: %v", err)
+			}
+			if err := executeCommit(ctx, mode, paths, "feat: add new file"); err != nil {
+				t.Fatalf("executeCommit returned error: %v", err)
+			}
+
+			want := "new file.txt"
+			if mode == "all" {
+				want = "gone.txt,new file.txt,tracked.txt"
+			}
+			committed := strings.Split(strings.TrimRight(git("show", "--format=", "--name-only", "--no-renames", "-z", "HEAD"), "\x00"), "\x00")
+			if strings.Join(committed, ",") != want {
+				t.Errorf("committed = %q, want %q", committed, want)
+			}
+			if staged := git("diff", "--cached", "--name-only"); staged != "pre.txt\n" {
+				t.Errorf("staged after the commit = %q, want pre.txt to stay staged", staged)
+			}
+		})
+	}
+}
+
+func TestPartlyStagedFindsFilesWithStagedChanges(t *testing.T) {
+	dir := initRepo(t)
+	for _, name := range []string{"partly.txt", "unstaged.txt"} {
+		writeTestFile(t, filepath.Join(dir, name))
+	}
+	for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", "chore: add files"}} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	for _, name := range []string{"partly.txt", "unstaged.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("staged\n"), 0o600); err != nil {
+			t.Fatalf("failed to change %s: %v", name, err)
+		}
+	}
+	if output, err := exec.Command("git", "add", "partly.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, output)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "partly.txt"), []byte("staged\nunstaged\n"), 0o600); err != nil {
+		t.Fatalf("failed to change partly.txt: %v", err)
+	}
+
+	ctx := context.Background()
+	_, paths, err := getAnalysisInput(ctx, "all", 8000)
+	if err != nil {
+		t.Fatalf("getAnalysisInput returned error: %v", err)
+	}
+	partly, err := partlyStaged(ctx, paths)
+	if err != nil {
+		t.Fatalf("partlyStaged returned error: %v", err)
+	}
+	if strings.Join(partly, ",") != "partly.txt" {
+		t.Errorf("partlyStaged = %q, want only partly.txt", partly)
+	}
+}
+
+func writeTestFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte("content\n"), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestAnalysisIncludesUnusualPaths(t *testing.T) {
+	dir := initRepo(t)
+	names := []string{"docs/Design (v2).md", `say "hi" & $HOME.txt`, "naïve.go", "tab\tname.txt"}
+	for _, name := range names {
+		writeTestFile(t, filepath.Join(dir, name))
+	}
+
+	ctx := context.Background()
+	analysis, paths, err := getAnalysisInput(ctx, "untracked", 8000)
+	if err != nil {
+		t.Fatalf("getAnalysisInput returned error: %v", err)
+	}
+	if len(paths) != len(names) {
+		t.Errorf("untracked paths = %q, want %q", paths, names)
+	}
+
+	if err := stagePaths(ctx, names); err != nil {
+		t.Fatalf("stagePaths returned error: %v", err)
+	}
+	staged, paths, err := getAnalysisInput(ctx, "staged", 8000)
+	if err != nil {
+		t.Fatalf("getAnalysisInput returned error: %v", err)
+	}
+	if len(paths) != len(names) {
+		t.Errorf("staged paths = %q, want %q", paths, names)
+	}
+
+	for _, name := range names {
+		if !strings.Contains(analysis, "--- "+changeset.QuotePath(name)+" (untracked, 1 line) ---") {
+			t.Errorf("untracked analysis is missing %q:\n%s", name, analysis)
+		}
+		header := "--- " + changeset.QuotePath(name) + " (added, 1 line) ---\n"
+		if i := strings.Index(staged, header); i < 0 || !strings.HasPrefix(staged[i+len(header):], "@@ -0,0 +1 @@\n+content\n") {
+			t.Errorf("staged analysis is missing the diff of %q:\n%s", name, staged)
+		}
+	}
+	if !strings.Contains(staged, `"tab\tname.txt"`) {
+		t.Errorf("file list does not quote the path with a tab:\n%s", staged)
+	}
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/FreePeak/commitgen/pkg/commitrules"
+	"github.com/FreePeak/commitgen/pkg/provider"
 )
 
 const (
-	providerClaude  = "claude"
-	providerGemini  = "gemini"
-	providerCopilot = "copilot"
+	providerClaude  = provider.NameClaude
+	providerGemini  = provider.NameGemini
+	providerCopilot = provider.NameCopilot
 )
 
 func TestCleanCommitMessage(t *testing.T) {
@@ -115,20 +117,6 @@ func getComplexTestCases() []struct {
 	}
 }
 
-// resolveProviderCommand returns the command for a given provider and whether it's a Claude provider.
-func resolveProviderCommand(provider string) (string, bool) {
-	switch {
-	case strings.HasPrefix(provider, providerClaude):
-		return providerClaude, true
-	case provider == providerGemini:
-		return providerGemini, false
-	case provider == providerCopilot:
-		return providerCopilot, false
-	default:
-		return "", false
-	}
-}
-
 // validateProviderMapping validates that a provider maps to the expected command.
 func validateProviderMapping(t *testing.T, provider, expectedCmd, actualCmd string, isClaude bool) {
 	switch {
@@ -177,7 +165,7 @@ func TestProviderValidation(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.provider, func(t *testing.T) {
-			command, isClaude := resolveProviderCommand(test.provider)
+			command, isClaude := provider.ResolveCommand(test.provider)
 			validateProviderMapping(t, test.provider, test.expectedCmd, command, isClaude)
 
 			// Validate expected behavior
@@ -188,6 +176,20 @@ func TestProviderValidation(t *testing.T) {
 	}
 }
 
+func TestProviderOptionsOnlyConfigureFirstProvider(t *testing.T) {
+	settings := generationSettings{
+		providers: []string{provider.NameAnthropic, provider.NameOllama},
+		options:   provider.Options{Model: "m", BaseURL: "http://gateway", APIKey: "sk-secret", MaxTokens: 100},
+	}
+	if first := settings.providerOptions(0); first != settings.options {
+		t.Errorf("first provider options = %+v, want %+v", first, settings.options)
+	}
+	want := provider.Options{MaxTokens: 100}
+	if fallback := settings.providerOptions(1); fallback != want {
+		t.Errorf("fallback provider options = %+v, want %+v", fallback, want)
+	}
+}
+
 func TestPromptStructure(t *testing.T) {
 	analysisInput := "feat(service): add new endpoint"
 
@@ -362,3 +364,25 @@ func BenchmarkProviderValidation(b *testing.B) {
 		}
 	}
 }
+
+func TestIsTerminalRejectsRedirects(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	if isTerminal(devNull) {
+		t.Errorf("%s should not be treated as a terminal", os.DevNull)
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer file.Close()
+
+	if isTerminal(file) {
+		t.Error("a regular file should not be treated as a terminal")
+	}
+}